@@ -0,0 +1,69 @@
+package instamojo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount is a sum of money in paise.
+// Instamojo reports amounts as decimal strings like "2500.00", Amount lets them be added up
+// without the rounding errors that come with float64
+type Amount int64
+
+// ParseAmount parses a decimal string like "2500.00" or "9.5" into an Amount
+func ParseAmount(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("invalid amount: empty string")
+	}
+
+	// Only a leading "-" is allowed, Both parts must be plain digits
+	neg := strings.HasPrefix(s, "-")
+	whole, frac, dot := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	if !digits(whole) || (dot && !digits(frac)) || len(frac) > 2 {
+		return 0, fmt.Errorf("invalid amount: %q", s)
+	}
+
+	rupees, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || rupees > math.MaxInt64/100-1 {
+		return 0, fmt.Errorf("invalid amount: %q", s)
+	}
+
+	paise := int64(0)
+	if frac != "" {
+		paise, err = strconv.ParseInt(frac+strings.Repeat("0", 2-len(frac)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount: %q", s)
+		}
+	}
+
+	a := Amount(rupees*100 + paise)
+	if neg {
+		a = -a
+	}
+	return a, nil
+}
+
+// digits reports whether s is made of one or more ASCII digits
+func digits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the Amount the way instamojo does, With two decimal places
+func (a Amount) String() string {
+	sign := ""
+	if a < 0 {
+		sign, a = "-", -a
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, a/100, a%100)
+}
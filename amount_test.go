@@ -0,0 +1,43 @@
+package instamojo_test
+
+import (
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestParseAmount(t *testing.T) {
+
+	tests := []struct {
+		in   string
+		want instamojo.Amount
+		err  bool
+	}{
+		{in: "2500.00", want: 250000},
+		{in: "9.5", want: 950},
+		{in: "10", want: 1000},
+		{in: "-0.05", want: -5},
+		{in: "1.005", err: true},
+		{in: "", err: true},
+		{in: "abc", err: true},
+		{in: "--5", err: true},
+		{in: "+5", err: true},
+		{in: "1.-5", err: true},
+		{in: "1.+5", err: true},
+		{in: "1. 5", err: true},
+		{in: "1.", err: true},
+		{in: ".5", err: true},
+		{in: "92233720368547758.07", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := instamojo.ParseAmount(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseAmount(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package instamojo
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTooManyRequests is returned when instamojo throttles the requests made with a Config
+var ErrTooManyRequests = errors.New("instamojo: too many requests")
+
+// BatchOptions controls how a batch of lookups is sent to instamojo.
+// Workers is the number of lookups in flight at once and defaults to 4.
+// Interval is the minimum time between two requests across all the workers.
+// A lookup that is throttled is retried up to Retries times, Waiting Backoff (1s by default)
+// before the first retry and twice as long before every next one
+type BatchOptions struct {
+	Workers  int
+	Interval time.Duration
+	Retries  int
+	Backoff  time.Duration
+}
+
+// PaymentResult is the result of looking up one payment in a batch
+type PaymentResult struct {
+	PaymentID string
+	Details   *PaymentDetails
+	Err       error
+}
+
+// PaymentRequestResult is the result of looking up one payment request in a batch
+type PaymentRequestResult struct {
+	PaymentRequestID string
+	Details          *PaymentRequestDetails
+	Err              error
+}
+
+// BatchPaymentDetails fetches PaymentDetails for all the paymentIDs.
+// Results are in the same order as paymentIDs, A failed lookup only sets Err on its own result
+func (c *Config) BatchPaymentDetails(paymentIDs []string, o *BatchOptions) []PaymentResult {
+
+	results := make([]PaymentResult, len(paymentIDs))
+	runBatch(len(paymentIDs), o, func(i int) error {
+		results[i].PaymentID = paymentIDs[i]
+		results[i].Details, results[i].Err = c.PaymentDetails(paymentIDs[i])
+		return results[i].Err
+	})
+
+	return results
+}
+
+// BatchPaymentRequestDetails fetches PaymentRequestDetails for all the paymentRequestIDs.
+// Results are in the same order as paymentRequestIDs, A failed lookup only sets Err on its own result
+func (c *Config) BatchPaymentRequestDetails(paymentRequestIDs []string, o *BatchOptions) []PaymentRequestResult {
+
+	results := make([]PaymentRequestResult, len(paymentRequestIDs))
+	runBatch(len(paymentRequestIDs), o, func(i int) error {
+		results[i].PaymentRequestID = paymentRequestIDs[i]
+		results[i].Details, results[i].Err = c.PaymentRequestDetails(paymentRequestIDs[i])
+		return results[i].Err
+	})
+
+	return results
+}
+
+// runBatch calls fn for 0 <= i < n on a pool of workers, Spacing the calls by o.Interval
+// and retrying the calls that fail with ErrTooManyRequests
+func runBatch(n int, o *BatchOptions, fn func(i int) error) {
+	if o == nil {
+		o = &BatchOptions{}
+	}
+	workers := o.Workers
+	if workers < 1 {
+		workers = 4
+	}
+	backoff := o.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	var tick <-chan time.Time
+	if o.Interval > 0 {
+		t := time.NewTicker(o.Interval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				for attempt := 0; ; attempt++ {
+					if tick != nil {
+						<-tick
+					}
+					err := fn(i)
+					if !errors.Is(err, ErrTooManyRequests) || attempt >= o.Retries {
+						break
+					}
+					time.Sleep(backoff << uint(attempt))
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
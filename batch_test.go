@@ -0,0 +1,67 @@
+package instamojo_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestBatchPaymentDetails(t *testing.T) {
+
+	var mu sync.Mutex
+	throttled := map[string]bool{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/1.1/payments/")
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case id == "MISSING":
+			w.WriteHeader(404)
+			return
+		case !throttled[id]:
+			// Throttle the first lookup of every payment
+			throttled[id] = true
+			w.WriteHeader(429)
+			return
+		}
+		fmt.Fprintf(w, `{"success": true, "payment": {"payment_id": %q, "status": "Credit"}}`, id)
+	}))
+	defer ts.Close()
+
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{"MOJO1", "MOJO2", "MISSING", "MOJO3", "MOJO4", "MOJO5"}
+	results := c.BatchPaymentDetails(ids, &instamojo.BatchOptions{
+		Workers:  3,
+		Interval: time.Millisecond,
+		Retries:  1,
+		Backoff:  time.Millisecond,
+	})
+
+	for i, r := range results {
+		if r.PaymentID != ids[i] {
+			t.Errorf("results[%d] is for %q, want %q", i, r.PaymentID, ids[i])
+		}
+
+		switch {
+		case r.PaymentID == "MISSING":
+			if r.Err == nil {
+				t.Error("missing payment has no error")
+			}
+		case r.Err != nil:
+			t.Errorf("%s: %v", r.PaymentID, r.Err)
+		case r.Details.Payment.PaymentID != r.PaymentID:
+			t.Errorf("Got payment %q for %q", r.Details.Payment.PaymentID, r.PaymentID)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+package instamojo
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// bulkColumns are the columns that BulkCreatePaymentURLs reads from the input CSV
+var bulkColumns = []string{"purpose", "amount", "buyer_name", "email", "phone"}
+
+// bulkOutputColumns are the columns of the CSV that BulkCreatePaymentURLs writes
+var bulkOutputColumns = append([]string{"row"}, append(bulkColumns, "payment_request_id", "shorturl", "longurl", "error", "status", "sent_at")...)
+
+// The statuses of a row in the output CSV. A row is marked as sending before its request is sent,
+// So a row whose last status is sending was interrupted while instamojo may have been creating its link
+const (
+	bulkSending   = "sending"
+	bulkCreated   = "created"
+	bulkFailed    = "failed"
+	bulkUncertain = "uncertain"
+)
+
+// ErrBulkUncertain is returned by BulkCreatePaymentURLs when some rows may or may not have been created
+var ErrBulkUncertain = errors.New("instamojo: some payment requests may have been created and need to be checked")
+
+// BulkOptions controls BulkCreatePaymentURLs.
+// Template is copied into every payment request before the row is filled in,
+// So fields like RedirectURL, Webhook or SendEmail can be set once for all the rows.
+// ResendUnmatched sends the uncertain rows that could not be matched with a payment request again,
+// It should only be set once they have been checked by hand
+type BulkOptions struct {
+	BatchOptions
+	Template        PaymentURLRequest
+	ResendUnmatched bool
+}
+
+type bulkRow struct {
+	line   int
+	fields []string
+}
+
+// key identifies a row in the input, So a resumed run can tell which rows are already done
+func (r bulkRow) key() string {
+	return strconv.Itoa(r.line) + "\x00" + strings.Join(r.fields, "\x00")
+}
+
+// bulkState is the last status of a row in the output of a previous run
+type bulkState struct {
+	status string
+	id     string
+	sentAt time.Time
+}
+
+// BulkCreatePaymentURLs creates a payment request for every row of the CSV read from in.
+// The first row is a header that must have the purpose, amount, buyer_name, email and phone columns.
+// Every row is validated before it is sent, The result of each row is appended to the CSV at outPath
+// as soon as it is known, With the payment request id, shorturl and longurl or the error, And its status.
+//
+// outPath is also what makes a run resumable. Rows that were created are skipped and rows that failed
+// before instamojo could have created them are sent again. Rows whose request may have gone through,
+// Because of a network error, A server error or a crash while it was in flight, Are never sent again blindly.
+// They are matched with the payment requests from ListRequests by purpose, amount and email instead,
+// And the ones that can not be matched are left with the uncertain status and make it return ErrBulkUncertain.
+// A record that was only partly written when the process died is dropped from the end of outPath
+func (c *Config) BulkCreatePaymentURLs(in io.Reader, outPath string, o *BulkOptions) error {
+	if o == nil {
+		o = &BulkOptions{}
+	}
+
+	rows, err := readBulkRows(in)
+	if err != nil {
+		return err
+	}
+
+	prev, resumed, err := readBulkOutput(outPath)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(outPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	out := &bulkWriter{f: f, w: csv.NewWriter(f)}
+	if !resumed {
+		if err := out.write(bulkOutputColumns); err != nil {
+			return err
+		}
+	}
+
+	claimed := make(map[string]bool)
+	for _, st := range prev {
+		if st.status == bulkCreated {
+			claimed[st.id] = true
+		}
+	}
+
+	pending := make([]bulkRow, 0, len(rows))
+	var unsure []bulkRow
+	for _, r := range rows {
+		switch prev[r.key()].status {
+		case bulkCreated:
+		case bulkSending, bulkUncertain:
+			unsure = append(unsure, r)
+		default:
+			pending = append(pending, r)
+		}
+	}
+
+	var uncertain int32
+	if len(unsure) > 0 {
+		unmatched, err := c.reconcileBulk(unsure, prev, claimed, out)
+		if err != nil {
+			return err
+		}
+		if o.ResendUnmatched {
+			pending = append(pending, unmatched...)
+		} else {
+			uncertain = int32(len(unmatched))
+		}
+	}
+
+	throttled := make([]bool, len(pending))
+	sentAt := make([]time.Time, len(pending))
+	runBatch(len(pending), &o.BatchOptions, func(i int) error {
+		r := pending[i]
+
+		p, err := bulkPaymentRequest(o.Template, r.fields)
+		if err != nil {
+			return out.result(r, bulkFailed, nil, err, time.Time{})
+		}
+
+		// The row must be marked before it is sent, Or a crash would make the next run send it twice
+		sentAt[i] = time.Now().UTC()
+		if err := out.result(r, bulkSending, nil, nil, sentAt[i]); err != nil {
+			return err
+		}
+
+		pr, err := c.CreatePaymentURL(p)
+		throttled[i] = errors.Is(err, ErrTooManyRequests)
+		switch {
+		case err == nil:
+			return out.result(r, bulkCreated, pr, nil, sentAt[i])
+		case throttled[i]:
+			// Recorded once the batch has given up on retrying it
+			return err
+		case mayHaveCreated(err):
+			atomic.AddInt32(&uncertain, 1)
+			return out.result(r, bulkUncertain, nil, err, sentAt[i])
+		}
+		return out.result(r, bulkFailed, nil, err, sentAt[i])
+	})
+
+	for i, t := range throttled {
+		if t {
+			out.result(pending[i], bulkFailed, nil, ErrTooManyRequests, sentAt[i])
+		}
+	}
+
+	if out.err != nil {
+		return out.err
+	}
+	if uncertain > 0 {
+		return fmt.Errorf("%w: %d rows in %s", ErrBulkUncertain, uncertain, outPath)
+	}
+	return nil
+}
+
+// mayHaveCreated reports whether a CreatePaymentURL that failed with err may have created the payment request anyway.
+// Only the responses in which instamojo rejected the request rule that out
+func mayHaveCreated(err error) bool {
+	var br *BadRequest
+	var ua *Unauthorized
+	return !errors.As(err, &br) && !errors.As(err, &ua) && !errors.Is(err, ErrTooManyRequests)
+}
+
+// reconcileBulk looks for the payment requests of rows that may have been created, And records the ones it finds
+// as created. It returns the rows that could not be matched
+func (c *Config) reconcileBulk(rows []bulkRow, prev map[string]bulkState, claimed map[string]bool, out *bulkWriter) ([]bulkRow, error) {
+
+	// Only the payment requests created since the earliest of the rows was sent can match,
+	// With a minute of leeway for the clocks
+	var since time.Time
+	for _, r := range rows {
+		t := prev[r.key()].sentAt
+		if t.IsZero() {
+			since = time.Time{}
+			break
+		}
+		if since.IsZero() || t.Before(since) {
+			since = t
+		}
+	}
+	if !since.IsZero() {
+		since = since.Add(-time.Minute)
+	}
+
+	const limit = 100
+	rl := &RequestsList{}
+	for page := 1; ; page++ {
+		l, err := c.ListRequestsWithOptions(&ListRequestsOptions{MinCreatedAt: since, Page: page, Limit: limit})
+		if err != nil {
+			return nil, fmt.Errorf("error in reconciling uncertain rows: %v", err)
+		}
+		rl.PaymentRequests = append(rl.PaymentRequests, l.PaymentRequests...)
+		if len(l.PaymentRequests) < limit {
+			break
+		}
+	}
+
+	var unmatched []bulkRow
+	for _, r := range rows {
+		purpose, amount, email := r.fields[0], r.fields[1], r.fields[3]
+		want, err := ParseAmount(amount)
+		if err != nil {
+			unmatched = append(unmatched, r)
+			continue
+		}
+
+		found := false
+		for _, p := range rl.PaymentRequests {
+			got, err := ParseAmount(p.Amount)
+			if claimed[p.ID] || err != nil || got != want || p.Purpose != purpose || p.Email != email {
+				continue
+			}
+
+			claimed[p.ID] = true
+			pr := &PaymentURLResponse{Success: true}
+			pr.PaymentRequest.ID = p.ID
+			pr.PaymentRequest.Shorturl = p.Shorturl
+			pr.PaymentRequest.Longurl = p.Longurl
+			if err := out.result(r, bulkCreated, pr, nil, prev[r.key()].sentAt); err != nil {
+				return nil, err
+			}
+			found = true
+			break
+		}
+
+		if !found {
+			unmatched = append(unmatched, r)
+		}
+	}
+
+	return unmatched, nil
+}
+
+func readBulkRows(in io.Reader) ([]bulkRow, error) {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error in reading csv header: %v", err)
+	}
+
+	index := make(map[string]int)
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, col := range bulkColumns {
+		if _, ok := index[col]; !ok {
+			return nil, fmt.Errorf("csv header has no %q column", col)
+		}
+	}
+
+	var rows []bulkRow
+	for line := 2; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fields := make([]string, len(bulkColumns))
+		for i, col := range bulkColumns {
+			if j := index[col]; j < len(rec) {
+				fields[i] = strings.TrimSpace(rec[j])
+			}
+		}
+		rows = append(rows, bulkRow{line: line, fields: fields})
+	}
+}
+
+// readBulkOutput returns the last state of every row in the output of a previous run,
+// And whether there was a previous run at all.
+// The last record is dropped, And cut off the file, If it was torn by a crash while it was being written.
+// A broken record anywhere else is an error
+func readBulkOutput(outPath string) (map[string]bulkState, bool, error) {
+	state := make(map[string]bulkState)
+
+	b, err := ioutil.ReadFile(outPath)
+	if os.IsNotExist(err) {
+		return state, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	r := csv.NewReader(bytes.NewReader(b))
+	r.FieldsPerRecord = -1
+
+	var records [][]string
+	var good int64
+	var torn error
+	for {
+		start := r.InputOffset()
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err == nil && len(rec) != len(bulkOutputColumns) {
+			err = fmt.Errorf("record at offset %d has %d fields", start, len(rec))
+		}
+		// A complete record always ends with a newline
+		if err == nil && r.InputOffset() == int64(len(b)) && !bytes.HasSuffix(b, []byte("\n")) {
+			err = fmt.Errorf("record at offset %d is not terminated", start)
+		}
+
+		if torn != nil {
+			return nil, false, fmt.Errorf("error in reading %s: %v", outPath, torn)
+		}
+		if err != nil {
+			torn = err
+			continue
+		}
+		records = append(records, rec)
+		good = r.InputOffset()
+	}
+
+	if torn != nil {
+		if err := os.Truncate(outPath, good); err != nil {
+			return nil, false, err
+		}
+	}
+	if len(records) == 0 {
+		return state, false, nil
+	}
+
+	for _, rec := range records[1:] {
+		line, err := strconv.Atoi(rec[0])
+		if err != nil {
+			return nil, false, fmt.Errorf("error in reading %s: invalid row %q", outPath, rec[0])
+		}
+
+		n := len(bulkColumns)
+		st := bulkState{status: rec[n+5], id: rec[n+1]}
+		if rec[n+6] != "" {
+			if st.sentAt, err = time.Parse(time.RFC3339Nano, rec[n+6]); err != nil {
+				return nil, false, fmt.Errorf("error in reading %s: invalid sent_at %q", outPath, rec[n+6])
+			}
+		}
+		state[bulkRow{line: line, fields: rec[1 : n+1]}.key()] = st
+	}
+
+	return state, true, nil
+}
+
+// bulkPaymentRequest validates a row and turns it into a PaymentURLRequest
+func bulkPaymentRequest(template PaymentURLRequest, fields []string) (*PaymentURLRequest, error) {
+	purpose, amount, name, email, phone := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	if purpose == "" {
+		return nil, fmt.Errorf("purpose is required")
+	}
+
+	a, err := strconv.Atoi(amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount %q, it must be a whole number of rupees", amount)
+	}
+	if a < 9 {
+		return nil, fmt.Errorf("amount must be at least 9")
+	}
+
+	if email != "" && !strings.Contains(email, "@") {
+		return nil, fmt.Errorf("invalid email %q", email)
+	}
+
+	if phone != "" {
+		digits := strings.TrimPrefix(phone, "+")
+		if len(digits) < 10 || strings.Trim(digits, "0123456789") != "" {
+			return nil, fmt.Errorf("invalid phone %q", phone)
+		}
+	}
+
+	p := template
+	p.Purpose = purpose
+	p.Amount = a
+	p.BuyerName = name
+	p.Email = email
+	p.Phone = phone
+	return &p, nil
+}
+
+// bulkWriter appends rows to the output CSV and flushes them to disk one at a time,
+// Keeping the first error it runs into
+type bulkWriter struct {
+	mu  sync.Mutex
+	f   *os.File
+	w   *csv.Writer
+	err error
+}
+
+func (b *bulkWriter) result(r bulkRow, status string, pr *PaymentURLResponse, err error, sentAt time.Time) error {
+	rec := append([]string{strconv.Itoa(r.line)}, r.fields...)
+	switch {
+	case err != nil:
+		rec = append(rec, "", "", "", err.Error())
+	case pr != nil:
+		rec = append(rec, pr.PaymentRequest.ID, pr.PaymentRequest.Shorturl, pr.PaymentRequest.Longurl, "")
+	default:
+		rec = append(rec, "", "", "", "")
+	}
+
+	at := ""
+	if !sentAt.IsZero() {
+		at = sentAt.Format(time.RFC3339Nano)
+	}
+	rec = append(rec, status, at)
+
+	if werr := b.write(rec); werr != nil {
+		return werr
+	}
+	return err
+}
+
+func (b *bulkWriter) write(rec []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return b.err
+	}
+
+	b.w.Write(rec)
+	b.w.Flush()
+	if b.err = b.w.Error(); b.err == nil {
+		b.err = b.f.Sync()
+	}
+	return b.err
+}
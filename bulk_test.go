@@ -0,0 +1,136 @@
+package instamojo_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestBulkCreatePaymentURLs(t *testing.T) {
+
+	type created struct {
+		ID      string `json:"id"`
+		Purpose string `json:"purpose"`
+		Amount  string `json:"amount"`
+		Email   string `json:"email"`
+	}
+
+	var mu sync.Mutex
+	var links []created
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Method == "GET" {
+			if r.URL.Query().Get("page") != "1" {
+				fmt.Fprint(w, `{"success": true, "payment_requests": []}`)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "payment_requests": links})
+			return
+		}
+
+		p := &instamojo.PaymentURLRequest{}
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			t.Error(err)
+			return
+		}
+
+		id := fmt.Sprintf("PR%d", len(links)+1)
+		links = append(links, created{ID: id, Purpose: p.Purpose, Amount: fmt.Sprintf("%d.00", p.Amount), Email: p.Email})
+
+		// The link is created but the response is lost
+		if p.Purpose == "Flaky" {
+			w.WriteHeader(502)
+			return
+		}
+		w.WriteHeader(201)
+		fmt.Fprintf(w, `{"success": true, "payment_request": {"id": %q, "purpose": %q, "shorturl": "https://imjo.in/%s", "longurl": "https://www.instamojo.com/@x/%s"}}`, id, p.Purpose, id, id)
+	}))
+	defer ts.Close()
+
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `purpose,amount,buyer_name,email,phone
+Workshop,500,John Doe,john@example.com,9999999999
+Meetup,abc,Jane Doe,jane@example.com,
+Flaky,2500,,flaky@example.com,
+`
+	out := filepath.Join(t.TempDir(), "out.csv")
+
+	// The first run can not tell whether the flaky row was created
+	err = c.BulkCreatePaymentURLs(strings.NewReader(input), out, &instamojo.BulkOptions{})
+	if !errors.Is(err, instamojo.ErrBulkUncertain) {
+		t.Fatalf("Got error %v, want %v", err, instamojo.ErrBulkUncertain)
+	}
+
+	// The second run finds it with ListRequests instead of creating it again
+	if err := c.BulkCreatePaymentURLs(strings.NewReader(input), out, &instamojo.BulkOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash left half a record at the end of the output
+	f, err := os.OpenFile(out, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(f, `2,Workshop,500,John Doe,"john@exa`)
+	f.Close()
+
+	if err := c.BulkCreatePaymentURLs(strings.NewReader(input), out, &instamojo.BulkOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(links) != 2 {
+		t.Errorf("Got %d payment requests, want 2: %v", len(links), links)
+	}
+
+	f, err = os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := map[string][]string{}
+	for _, rec := range records[1:] {
+		last[rec[1]] = rec
+	}
+
+	// The workers run concurrently, So the ids are looked up by purpose
+	ids := map[string]string{}
+	for _, l := range links {
+		ids[l.Purpose] = l.ID
+	}
+	want := map[string][2]string{
+		"Workshop": {ids["Workshop"], "created"},
+		"Meetup":   {"", "failed"},
+		"Flaky":    {ids["Flaky"], "created"},
+	}
+	for purpose, w := range want {
+		rec := last[purpose]
+		if rec == nil || rec[6] != w[0] || rec[10] != w[1] {
+			t.Errorf("Got %q for %s, want %v", rec, purpose, w)
+		}
+	}
+	if last["Meetup"][9] == "" {
+		t.Errorf("invalid row has no error: %q", last["Meetup"])
+	}
+}
@@ -0,0 +1,198 @@
+package instamojo
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cache is the backend that Config uses to cache responses.
+// Values are JSON encoded responses, A ttl of zero means that the value never expires.
+// Implementations must be safe for concurrent use
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// LRUCache is an in-memory Cache that holds at most a fixed number of entries,
+// Evicting the least recently used one when it is full
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRUCache creates an LRUCache that holds at most size entries
+func NewLRUCache(size int) *LRUCache {
+	if size < 1 {
+		size = 1
+	}
+
+	return &LRUCache{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value stored for key, If it has not expired
+func (l *LRUCache) Get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*lruEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		l.ll.Remove(el)
+		delete(l.entries, key)
+		return nil, false
+	}
+
+	l.ll.MoveToFront(el)
+	return e.value, true
+}
+
+// Set stores value for key, Replacing any previous value
+func (l *LRUCache) Set(key string, value []byte, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := &lruEntry{key: key, value: value}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+
+	if el, ok := l.entries[key]; ok {
+		el.Value = e
+		l.ll.MoveToFront(el)
+		return
+	}
+
+	l.entries[key] = l.ll.PushFront(e)
+	if l.ll.Len() > l.size {
+		oldest := l.ll.Back()
+		l.ll.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// paymentFinal reports whether a payment with status will not change anymore
+func paymentFinal(status string) bool {
+	return status == "Credit" || status == "Failed"
+}
+
+// cached decodes the value cached for key into dst.
+// On a miss it calls fetch, Concurrent misses for the same key share a single call.
+// fetch reports whether the value is final, Which decides how long it is cached for
+func (c *Config) cached(key string, dst interface{}, fetch func() (interface{}, bool, error)) error {
+	key, err := c.cacheKey(key)
+	if err != nil {
+		return err
+	}
+
+	if b, ok := c.Cache.Get(key); ok {
+		return json.Unmarshal(b, dst)
+	}
+
+	b, err := c.flights().do(key, func() ([]byte, error) {
+		v, final, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case final:
+			c.Cache.Set(key, b, 0)
+		case c.CacheTTL > 0:
+			c.Cache.Set(key, b, c.CacheTTL)
+		}
+		return b, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, dst)
+}
+
+// cacheKey scopes key to the endpoint, API version and account of the Config,
+// So Configs of different merchants or environments can share a Cache without seeing each other's entries.
+// The API key is hashed to keep it out of the keys of caches that are stored elsewhere
+func (c *Config) cacheKey(key string) (string, error) {
+	creds, err := c.credentials()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.Sum256([]byte(creds.APIKey))
+	return fmt.Sprintf("%s/api/%s/%s/%s", c.endpoint, c.APIVersion, hex.EncodeToString(h[:8]), key), nil
+}
+
+// sharedFlight is used by the Configs that were not made with Init, Which can share it because cache keys are scoped to the account
+var sharedFlight = &flightGroup{}
+
+// flights returns the flightGroup that concurrent cache misses of c are collapsed in
+func (c *Config) flights() *flightGroup {
+	if c.flight == nil {
+		return sharedFlight
+	}
+	return c.flight
+}
+
+// flightGroup collapses concurrent calls with the same key into one
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg  sync.WaitGroup
+	val []byte
+	err error
+}
+
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if fc, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		fc.wg.Wait()
+		return fc.val, fc.err
+	}
+
+	fc := &flightCall{}
+	fc.wg.Add(1)
+	g.calls[key] = fc
+	g.mu.Unlock()
+
+	fc.val, fc.err = fn()
+	fc.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	return fc.val, fc.err
+}
@@ -0,0 +1,127 @@
+package instamojo_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestPaymentDetailsCache(t *testing.T) {
+
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		// Keep the request in flight long enough for the lookups to pile up
+		time.Sleep(50 * time.Millisecond)
+
+		status := "Credit"
+		if r.URL.Path == "/api/1.1/payments/MOJO2" {
+			status = "Pending"
+		}
+		fmt.Fprintf(w, `{"success": true, "payment": {"payment_id": "MOJO", "status": %q}}`, status)
+	}))
+	defer ts.Close()
+
+	c, err := instamojo.Init(&instamojo.Config{
+		APIKey:    "key",
+		AuthToken: "token",
+		BaseURL:   ts.URL,
+		Cache:     instamojo.NewLRUCache(10),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.PaymentDetails("MOJO1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := c.PaymentDetails("MOJO1"); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("Got %d requests for a credited payment, want 1", hits)
+	}
+
+	// Payments that are not final are not cached without a CacheTTL
+	for i := 0; i < 2; i++ {
+		if _, err := c.PaymentDetails("MOJO2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if atomic.LoadInt32(&hits) != 3 {
+		t.Errorf("Got %d requests, want 3", hits)
+	}
+
+	// A Config that was not made with Init does not panic on a cache miss
+	hand := &instamojo.Config{APIKey: "key", AuthToken: "token", Cache: instamojo.NewLRUCache(10)}
+	if _, err := hand.PaymentDetails("MOJO1"); err == nil {
+		t.Error("Got no error for a Config without an endpoint")
+	}
+}
+
+func TestSharedCache(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the first merchant has made this payment
+		if r.Header.Get("X-Api-Key") != "alpha" {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO1", "status": "Credit"}}`)
+	}))
+	defer ts.Close()
+
+	cache := instamojo.NewLRUCache(10)
+	alpha, err := instamojo.NewClient("alpha", "token", instamojo.WithBaseURL(ts.URL), instamojo.WithCache(cache, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	beta, err := instamojo.NewClient("beta", "token", instamojo.WithBaseURL(ts.URL), instamojo.WithCache(cache, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := alpha.PaymentDetails("MOJO1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := beta.PaymentDetails("MOJO1"); err == nil {
+		t.Error("Got the payment of another merchant from the cache")
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+
+	l := instamojo.NewLRUCache(2)
+	l.Set("a", []byte("1"), 0)
+	l.Set("b", []byte("2"), 0)
+	l.Get("a")
+	l.Set("c", []byte("3"), 0)
+
+	if _, ok := l.Get("b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	if _, ok := l.Get("a"); !ok {
+		t.Error("recently used entry was evicted")
+	}
+
+	l.Set("d", []byte("4"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok := l.Get("d"); ok {
+		t.Error("expired entry was returned")
+	}
+}
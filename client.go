@@ -0,0 +1,265 @@
+package instamojo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Client is an immutable alternative to Config.
+// It is built once by NewClient and can not be changed afterwards, So it is safe to share between goroutines
+type Client struct {
+	config Config
+}
+
+// Option configures a Client in NewClient
+type Option func(*Config)
+
+// WithSandbox makes the Client use instamojo's sandbox, test.instamojo.com
+func WithSandbox() Option {
+	return func(c *Config) {
+		c.SandboxMode = true
+	}
+}
+
+// WithBaseURL makes the Client send requests to baseURL instead of instamojo
+func WithBaseURL(baseURL string) Option {
+	return func(c *Config) {
+		c.BaseURL = baseURL
+	}
+}
+
+// WithAPIVersion sets the version of the API that the Client uses
+func WithAPIVersion(version string) Option {
+	return func(c *Config) {
+		c.APIVersion = version
+	}
+}
+
+// WithHTTPClient sets the http.Client that requests are sent with
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Config) {
+		c.HTTPClient = hc
+	}
+}
+
+// WithRetries sets how many times a failed request is sent again
+func WithRetries(n int) Option {
+	return func(c *Config) {
+		c.Retries = n
+	}
+}
+
+// WithLogger logs every request to l
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Config) {
+		c.Logger = l
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request
+func WithUserAgent(ua string) Option {
+	return func(c *Config) {
+		c.UserAgent = ua
+	}
+}
+
+// WithMiddleware wraps every request in m, In the order they are given
+func WithMiddleware(m ...Middleware) Option {
+	return func(c *Config) {
+		c.Middleware = append(c.Middleware[:len(c.Middleware):len(c.Middleware)], m...)
+	}
+}
+
+// WithCache caches payments and refunds in cache, See Config for how ttl is used
+func WithCache(cache Cache, ttl time.Duration) Option {
+	return func(c *Config) {
+		c.Cache = cache
+		c.CacheTTL = ttl
+	}
+}
+
+// WithStore saves every payment request, payment, refund and webhook in store.
+// onError is called with the errors in saving them, It can be nil
+func WithStore(store Store, onError func(error)) Option {
+	return func(c *Config) {
+		c.Store = store
+		c.OnStoreError = onError
+	}
+}
+
+// WithEvents publishes the payment requests, refunds and webhooks of the Client on bus
+func WithEvents(bus *EventBus) Option {
+	return func(c *Config) {
+		c.Events = bus
+	}
+}
+
+// WithMetrics counts the requests and webhooks of the Client in m
+func WithMetrics(m *Metrics) Option {
+	return func(c *Config) {
+		c.Metrics = m
+	}
+}
+
+// WithCredentials gets the API key and auth token from p on every request,
+// Instead of the ones passed to NewClient, Which can then be empty
+func WithCredentials(p CredentialsProvider) Option {
+	return func(c *Config) {
+		c.Credentials = p
+	}
+}
+
+// WithPrivateSalt sets the salt that the webhooks of the account are signed with
+func WithPrivateSalt(salt string) Option {
+	return func(c *Config) {
+		c.PrivateSalt = salt
+	}
+}
+
+// NewClient creates a Client with apiKey and authToken
+func NewClient(apiKey, authToken string, opts ...Option) (*Client, error) {
+	c := Config{APIKey: apiKey, AuthToken: authToken}
+	for _, o := range opts {
+		o(&c)
+	}
+
+	if c.Retries < 0 {
+		return nil, fmt.Errorf("invalid retries: %d", c.Retries)
+	}
+	if c.CacheTTL < 0 {
+		return nil, fmt.Errorf("invalid cache ttl: %v", c.CacheTTL)
+	}
+	if _, err := Init(&c); err != nil {
+		return nil, err
+	}
+
+	return &Client{config: c}, nil
+}
+
+// Config returns a copy of the Config that the Client was built from,
+// For the helpers that take a *Config. Changing its fields, Middleware included, Does not change the Client,
+// But the Cache, Store, Events, Metrics and Credentials it points to are shared with the Client
+func (cl *Client) Config() *Config {
+	c := cl.config
+	c.Middleware = append([]Middleware(nil), c.Middleware...)
+	return &c
+}
+
+// CreatePaymentURL creates a new Payment URL
+func (cl *Client) CreatePaymentURL(p *PaymentURLRequest) (*PaymentURLResponse, error) {
+	return cl.config.CreatePaymentURL(p)
+}
+
+// ListRequests returns all the payment requests created so far
+func (cl *Client) ListRequests() (*RequestsList, error) {
+	return cl.config.ListRequests()
+}
+
+// ListRequestsWithOptions is ListRequests with filters on the created and modified times, And pagination
+func (cl *Client) ListRequestsWithOptions(o *ListRequestsOptions) (*RequestsList, error) {
+	return cl.config.ListRequestsWithOptions(o)
+}
+
+// PaymentRequestDetails fetches details about a payment request ID
+func (cl *Client) PaymentRequestDetails(id string) (*PaymentRequestDetails, error) {
+	return cl.config.PaymentRequestDetails(id)
+}
+
+// CreateRefundRequest creates a refund request
+func (cl *Client) CreateRefundRequest(r *CreateRefundRequest) (*CreateRefundResponse, error) {
+	return cl.config.CreateRefundRequest(r)
+}
+
+// ListRefunds returns a list of all the refunds made so far
+func (cl *Client) ListRefunds() (*RefundsList, error) {
+	return cl.config.ListRefunds()
+}
+
+// RefundDetails can be used to retrieve details about a refund
+func (cl *Client) RefundDetails(refundID string) (*RefundDetails, error) {
+	return cl.config.RefundDetails(refundID)
+}
+
+// PaymentDetails is used to fetch details about a payment
+func (cl *Client) PaymentDetails(paymentID string) (*PaymentDetails, error) {
+	return cl.config.PaymentDetails(paymentID)
+}
+
+// DisableRequest disables a Payment Request
+func (cl *Client) DisableRequest(paymentRequestID string) (*SuccessResponse, error) {
+	return cl.config.DisableRequest(paymentRequestID)
+}
+
+// EnableRequest enables a Payment Request
+func (cl *Client) EnableRequest(paymentRequestID string) (*SuccessResponse, error) {
+	return cl.config.EnableRequest(paymentRequestID)
+}
+
+// ConfirmRedirect checks a redirect with instamojo, See Config.ConfirmRedirect
+func (cl *Client) ConfirmRedirect(r *RedirectResponse, paymentRequestID string) (*PaymentDetails, error) {
+	return cl.config.ConfirmRedirect(r, paymentRequestID)
+}
+
+// RedirectHandler returns a http.Handler for the RedirectURL, See Config.RedirectHandler
+func (cl *Client) RedirectHandler(
+	expected func(*http.Request) string,
+	success func(http.ResponseWriter, *http.Request, *PaymentDetails),
+	failure func(http.ResponseWriter, *http.Request, error),
+) http.Handler {
+	return cl.config.RedirectHandler(expected, success, failure)
+}
+
+// RefundableBalance returns how much of a payment can still be refunded
+func (cl *Client) RefundableBalance(paymentID string) (*RefundBalance, error) {
+	return cl.config.RefundableBalance(paymentID)
+}
+
+// CreateCheckedRefund creates a refund after checking that it does not exceed the refundable balance
+func (cl *Client) CreateCheckedRefund(r *CreateRefundRequest) (*CreateRefundResponse, error) {
+	return cl.config.CreateCheckedRefund(r)
+}
+
+// BatchPaymentDetails fetches PaymentDetails for many payments, See Config.BatchPaymentDetails
+func (cl *Client) BatchPaymentDetails(paymentIDs []string, o *BatchOptions) []PaymentResult {
+	return cl.config.BatchPaymentDetails(paymentIDs, o)
+}
+
+// BatchPaymentRequestDetails fetches PaymentRequestDetails for many payment requests
+func (cl *Client) BatchPaymentRequestDetails(paymentRequestIDs []string, o *BatchOptions) []PaymentRequestResult {
+	return cl.config.BatchPaymentRequestDetails(paymentRequestIDs, o)
+}
+
+// BulkCreatePaymentURLs creates payment requests from a CSV, See Config.BulkCreatePaymentURLs
+func (cl *Client) BulkCreatePaymentURLs(in io.Reader, outPath string, o *BulkOptions) error {
+	return cl.config.BulkCreatePaymentURLs(in, outPath, o)
+}
+
+// ParseWebhookResponse parses a webhook, Saving and publishing it if the Client is set up to
+func (cl *Client) ParseWebhookResponse(u url.Values) (*WebhookResponse, error) {
+	return cl.config.ParseWebhookResponse(u)
+}
+
+// VerifyWebhook checks the mac of a webhook against the private salt of the Client
+func (cl *Client) VerifyWebhook(u url.Values) bool {
+	return VerifyWebhookMAC(u, cl.config.PrivateSalt)
+}
+
+// WebhookHandler returns a http.Handler for the webhook URL, See Config.WebhookHandler
+func (cl *Client) WebhookHandler(salt string, fn func(*WebhookResponse)) http.Handler {
+	return cl.config.WebhookHandler(salt, fn)
+}
+
+// Do calls any endpoint of the API, See Config.Do
+func (cl *Client) Do(ctx context.Context, method, path string, body, out interface{}) error {
+	return cl.config.Do(ctx, method, path, body, out)
+}
+
+// VerifyCredentials checks the credentials of the Client, See Config.VerifyCredentials
+func (cl *Client) VerifyCredentials() error {
+	return cl.config.VerifyCredentials()
+}
@@ -0,0 +1,73 @@
+package instamojo_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestNewClient(t *testing.T) {
+
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "shop/1.0" {
+			t.Errorf("Got User-Agent %q", r.Header.Get("User-Agent"))
+		}
+		// The first two requests fail and have to be retried
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(502)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO1", "status": "Credit"}}`)
+	}))
+	defer ts.Close()
+
+	if _, err := instamojo.NewClient("", "token"); err == nil {
+		t.Error("client created without an api key")
+	}
+	if _, err := instamojo.NewClient("key", "token", instamojo.WithBaseURL("not a url")); err == nil {
+		t.Error("client created with an invalid base url")
+	}
+
+	cl, err := instamojo.NewClient("key", "token",
+		instamojo.WithBaseURL(ts.URL),
+		instamojo.WithHTTPClient(ts.Client()),
+		instamojo.WithRetries(2),
+		instamojo.WithUserAgent("shop/1.0"),
+		instamojo.WithMiddleware(func(next instamojo.RoundTripFunc) instamojo.RoundTripFunc { return next }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing the copy does not change the client
+	cfg := cl.Config()
+	cfg.APIKey = "changed"
+	cfg.Middleware[0] = func(next instamojo.RoundTripFunc) instamojo.RoundTripFunc {
+		return func(op instamojo.Operation, req *http.Request) (*http.Response, error) {
+			return nil, errors.New("middleware of the copy")
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cl.PaymentDetails("MOJO1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cl.Config().APIKey != "key" {
+		t.Errorf("Got api key %q, want key", cl.Config().APIKey)
+	}
+}
@@ -0,0 +1,163 @@
+package instamojo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Credentials are the API key and auth token of an instamojo account
+type Credentials struct {
+	APIKey    string `json:"api_key"`
+	AuthToken string `json:"auth_token"`
+}
+
+// CredentialsProvider gives the credentials to use for a request.
+// It is called for every request, So it should be cheap and safe for concurrent use
+type CredentialsProvider interface {
+	Credentials() (Credentials, error)
+}
+
+// CredentialsRefresher is implemented by the providers that can reload their credentials.
+// A request that is rejected as unauthorized refreshes the credentials and is sent once more
+type CredentialsRefresher interface {
+	Refresh() error
+}
+
+// credentials returns the credentials for a request
+func (c *Config) credentials() (Credentials, error) {
+	if c.Credentials == nil {
+		return Credentials{APIKey: c.APIKey, AuthToken: c.AuthToken}, nil
+	}
+
+	creds, err := c.Credentials.Credentials()
+	if err != nil {
+		return Credentials{}, fmt.Errorf("error in getting credentials: %v", err)
+	}
+	if creds.APIKey == "" || creds.AuthToken == "" {
+		return Credentials{}, fmt.Errorf("invalid tokens")
+	}
+	return creds, nil
+}
+
+// StaticCredentials is a CredentialsProvider that always gives the same credentials
+type StaticCredentials Credentials
+
+// Credentials returns s
+func (s StaticCredentials) Credentials() (Credentials, error) {
+	return Credentials(s), nil
+}
+
+// EnvCredentials is a CredentialsProvider that reads the credentials from environment variables
+// on every request. APIKeyVar and AuthTokenVar default to INSTAMOJO_API_KEY and INSTAMOJO_AUTH_TOKEN
+type EnvCredentials struct {
+	APIKeyVar    string
+	AuthTokenVar string
+}
+
+// Credentials reads the credentials from the environment
+func (e EnvCredentials) Credentials() (Credentials, error) {
+	keyVar, tokenVar := e.APIKeyVar, e.AuthTokenVar
+	if keyVar == "" {
+		keyVar = "INSTAMOJO_API_KEY"
+	}
+	if tokenVar == "" {
+		tokenVar = "INSTAMOJO_AUTH_TOKEN"
+	}
+
+	return Credentials{APIKey: os.Getenv(keyVar), AuthToken: os.Getenv(tokenVar)}, nil
+}
+
+// FileCredentials is a CredentialsProvider that reads the credentials from a JSON file,
+// Like {"api_key": "...", "auth_token": "..."}, And reloads them when the file changes
+type FileCredentials struct {
+	path  string
+	creds atomic.Value
+
+	mu      sync.Mutex
+	modTime time.Time
+	stop    chan struct{}
+	closed  sync.Once
+}
+
+// NewFileCredentials loads the credentials from path and checks it for changes every interval.
+// Call Close to stop watching the file
+func NewFileCredentials(path string, interval time.Duration) (*FileCredentials, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid interval: %v", interval)
+	}
+
+	f := &FileCredentials{path: path, stop: make(chan struct{})}
+	if err := f.Refresh(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-t.C:
+				// A file that is being rewritten may not parse, The old credentials are kept until it does
+				f.reload(false)
+			}
+		}
+	}()
+
+	return f, nil
+}
+
+// Credentials returns the credentials that were last loaded from the file
+func (f *FileCredentials) Credentials() (Credentials, error) {
+	return f.creds.Load().(Credentials), nil
+}
+
+// Refresh loads the credentials from the file again
+func (f *FileCredentials) Refresh() error {
+	return f.reload(true)
+}
+
+// Close stops watching the file, It can be called more than once
+func (f *FileCredentials) Close() error {
+	f.closed.Do(func() {
+		close(f.stop)
+	})
+	return nil
+}
+
+// reload loads the file if force is set or it has changed since the last load
+func (f *FileCredentials) reload(force bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	if !force && fi.ModTime().Equal(f.modTime) {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+
+	creds := Credentials{}
+	if err := json.Unmarshal(b, &creds); err != nil {
+		return fmt.Errorf("error in reading %s: %v", f.path, err)
+	}
+	if creds.APIKey == "" || creds.AuthToken == "" {
+		return fmt.Errorf("error in reading %s: invalid tokens", f.path)
+	}
+
+	f.creds.Store(creds)
+	f.modTime = fi.ModTime()
+	return nil
+}
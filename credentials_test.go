@@ -0,0 +1,62 @@
+package instamojo_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestFileCredentialsRotation(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "new-key" || r.Header.Get("X-Auth-Token") != "new-token" {
+			w.WriteHeader(401)
+			fmt.Fprint(w, `{"success": false, "message": "Invalid token."}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO1", "status": "Credit"}}`)
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := ioutil.WriteFile(path, []byte(`{"api_key": "old-key", "auth_token": "old-token"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := instamojo.NewFileCredentials(path, 0); err == nil {
+		t.Error("watching the file without an interval")
+	}
+
+	// Watch too slowly to notice the change, So only the refresh after the 401 can pick it up
+	creds, err := instamojo.NewFileCredentials(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Closing twice must not panic
+	defer creds.Close()
+	defer creds.Close()
+
+	cl, err := instamojo.NewClient("", "", instamojo.WithBaseURL(ts.URL), instamojo.WithCredentials(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"api_key": "new-key", "auth_token": "new-token"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cl.PaymentDetails("MOJO1"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := creds.Credentials()
+	if got.APIKey != "new-key" {
+		t.Errorf("Got api key %q, want new-key", got.APIKey)
+	}
+}
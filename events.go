@@ -0,0 +1,299 @@
+package instamojo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of an Event
+type EventType string
+
+// The events published on an EventBus
+const (
+	EventPaymentRequestCreated EventType = "payment_request.created"
+	EventPaymentCredited       EventType = "payment.credited"
+	EventPaymentFailed         EventType = "payment.failed"
+	EventRefundCreated         EventType = "refund.created"
+	EventRefundStatusChanged   EventType = "refund.status_changed"
+)
+
+// Event is a change in a payment request, payment or refund.
+// ID is the id of the object that changed and Data is the response it was found in,
+// One of *PaymentURLResponse, *WebhookResponse, *PaymentRequestDetails, *CreateRefundResponse or *RefundDetails
+type Event struct {
+	Type   EventType
+	ID     string
+	Status string
+	Time   time.Time
+	Data   interface{}
+}
+
+// EventBus delivers events to its subscribers.
+// The same change can be seen on a webhook and while polling, So an event is only delivered
+// the first time its Type, ID and Status are published within DedupWindow, Which defaults to 24 hours.
+// At most MaxSeen events, 100000 by default, Are remembered, The oldest are forgotten first.
+// Set them before the EventBus is used. The zero value is ready to use
+type EventBus struct {
+	DedupWindow time.Duration
+	MaxSeen     int
+
+	mu     sync.Mutex
+	subs   map[int]func(Event)
+	nextID int
+	seen   map[eventKey]bool
+	order  []seenEvent
+}
+
+type eventKey struct {
+	Type   EventType
+	ID     string
+	Status string
+}
+
+// seenEvent is when an event was first published, order holds them oldest first
+type seenEvent struct {
+	key eventKey
+	at  time.Time
+}
+
+// NewEventBus creates an EventBus with no subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[int]func(Event)), seen: make(map[eventKey]bool)}
+}
+
+// forget drops the events that are older than the window or over the limit, b.mu must be held
+func (b *EventBus) forget(now time.Time) {
+	window := b.DedupWindow
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	max := b.MaxSeen
+	if max <= 0 {
+		max = 100000
+	}
+
+	n := 0
+	for n < len(b.order) && (len(b.order)-n > max || now.Sub(b.order[n].at) >= window) {
+		delete(b.seen, b.order[n].key)
+		n++
+	}
+	b.order = b.order[n:]
+	if len(b.order) == 0 {
+		b.order = nil
+	}
+}
+
+// Subscribe calls fn with every event until the returned function is called.
+// fn is called on the goroutine that published the event
+func (b *EventBus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs == nil {
+		b.subs = make(map[int]func(Event))
+	}
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = fn
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Channel returns a channel that receives every event until the returned function is called,
+// Which also closes the channel. Publishing blocks while the channel is full
+func (b *EventBus) Channel(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	var mu sync.Mutex
+	done := make(chan struct{})
+	unsubscribe := b.Subscribe(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		select {
+		case <-done:
+		case ch <- e:
+		}
+	})
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			close(done)
+			unsubscribe()
+
+			// Wait for a publish that is in progress before closing ch
+			mu.Lock()
+			defer mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers e to all the subscribers, Unless an event with the same Type, ID and Status
+// has been published within the dedup window
+func (b *EventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	key := eventKey{Type: e.Type, ID: e.ID, Status: e.Status}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.forget(now)
+	if b.seen[key] {
+		b.mu.Unlock()
+		return
+	}
+	if b.seen == nil {
+		b.seen = make(map[eventKey]bool)
+	}
+	b.seen[key] = true
+	b.order = append(b.order, seenEvent{key: key, at: now})
+	b.forget(now)
+
+	subs := make([]func(Event), 0, len(b.subs))
+	for _, fn := range b.subs {
+		subs = append(subs, fn)
+	}
+	b.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(e)
+	}
+}
+
+// PublishWebhook publishes EventPaymentCredited or EventPaymentFailed for a webhook
+func (b *EventBus) PublishWebhook(w *WebhookResponse) {
+	b.publishPayment(w.PaymentID, w.Status, w)
+}
+
+func (b *EventBus) publishPayment(paymentID, status string, data interface{}) {
+	switch status {
+	case "Credit":
+		b.Publish(Event{Type: EventPaymentCredited, ID: paymentID, Status: status, Data: data})
+	case "Failed":
+		b.Publish(Event{Type: EventPaymentFailed, ID: paymentID, Status: status, Data: data})
+	}
+}
+
+// publish publishes e on c.Events if there is one
+func (c *Config) publish(e Event) {
+	if c.Events != nil {
+		c.Events.Publish(e)
+	}
+}
+
+// Poller publishes the events that have no webhook, Or whose webhook may have been missed,
+// By polling the payment requests and refunds that it watches
+type Poller struct {
+	Config *Config
+	Bus    *EventBus
+
+	mu       sync.Mutex
+	requests map[string]bool
+	refunds  map[string]bool
+}
+
+// NewPoller creates a Poller that publishes on bus
+func NewPoller(c *Config, bus *EventBus) *Poller {
+	return &Poller{Config: c, Bus: bus, requests: make(map[string]bool), refunds: make(map[string]bool)}
+}
+
+// WatchPaymentRequest polls paymentRequestID for new payments until UnwatchPaymentRequest is called
+func (p *Poller) WatchPaymentRequest(paymentRequestID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests[paymentRequestID] = true
+}
+
+// UnwatchPaymentRequest stops polling paymentRequestID
+func (p *Poller) UnwatchPaymentRequest(paymentRequestID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.requests, paymentRequestID)
+}
+
+// WatchRefund polls refundID for status changes until it reaches a final status
+func (p *Poller) WatchRefund(refundID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refunds[refundID] = true
+}
+
+// Poll fetches every watched payment request and refund once and publishes what changed.
+// It keeps going when a lookup fails and returns the first error
+func (p *Poller) Poll() error {
+	p.mu.Lock()
+	requests := make([]string, 0, len(p.requests))
+	for id := range p.requests {
+		requests = append(requests, id)
+	}
+	refunds := make([]string, 0, len(p.refunds))
+	for id := range p.refunds {
+		refunds = append(refunds, id)
+	}
+	p.mu.Unlock()
+
+	var first error
+	for _, id := range requests {
+		prd, err := p.Config.PaymentRequestDetails(id)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+
+		for _, pay := range prd.PaymentRequest.Payments {
+			p.Bus.publishPayment(pay.PaymentID, pay.Status, prd)
+		}
+	}
+
+	for _, id := range refunds {
+		rd, err := p.Config.RefundDetails(id)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+
+		p.Bus.Publish(Event{Type: EventRefundStatusChanged, ID: id, Status: rd.Refund.Status, Data: rd})
+		if refundFinal(rd.Refund.Status) {
+			p.mu.Lock()
+			delete(p.refunds, id)
+			p.mu.Unlock()
+		}
+	}
+
+	return first
+}
+
+// Run calls Poll every interval until ctx is done. Errors from Poll are passed to onError if it is not nil
+func (p *Poller) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval: %v", interval)
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := p.Poll(); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package instamojo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestEventBus(t *testing.T) {
+
+	refundStatus := "Pending"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/1.1/refunds/") {
+			fmt.Fprintf(w, `{"success": true, "refund": {"id": "C1", "status": %q}}`, refundStatus)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "payment_request": {"id": "PR1", "payments": [
+			{"payment_id": "MOJO1", "status": "Credit"},
+			{"payment_id": "MOJO2", "status": "Failed"}
+		]}}`)
+	}))
+	defer ts.Close()
+
+	bus := instamojo.NewEventBus()
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: ts.URL, Events: bus, PrivateSalt: "salt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch, stop := bus.Channel(10)
+
+	// A forged failure of MOJO2 is not published, And does not hide the real one
+	if _, err := c.ParseWebhookResponse(url.Values{"payment_id": []string{"MOJO2"}, "status": []string{"Failed"}}); !errors.Is(err, instamojo.ErrInvalidMAC) {
+		t.Errorf("Got error %v for a webhook without a mac", err)
+	}
+
+	// The credit of MOJO1 arrives on the webhook and again while polling
+	if _, err := c.ParseWebhookResponse(instamojo.SignWebhookResponse(&instamojo.WebhookResponse{PaymentID: "MOJO1", Status: "Credit"}, "salt")); err != nil {
+		t.Fatal(err)
+	}
+
+	p := instamojo.NewPoller(c, bus)
+	p.WatchPaymentRequest("PR1")
+	p.WatchRefund("C1")
+	for _, status := range []string{"Pending", "Pending", "Refunded"} {
+		refundStatus = status
+		if err := p.Poll(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stop()
+
+	if err := p.Run(context.Background(), 0, nil); err == nil {
+		t.Error("ran with a zero interval")
+	}
+
+	var got []string
+	for e := range ch {
+		got = append(got, fmt.Sprintf("%s %s %s", e.Type, e.ID, e.Status))
+	}
+
+	want := []string{
+		"payment.credited MOJO1 Credit",
+		"payment.failed MOJO2 Failed",
+		"refund.status_changed C1 Pending",
+		"refund.status_changed C1 Refunded",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Got events\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestEventBusDedupWindow(t *testing.T) {
+
+	// The zero value is usable with the fields set directly
+	bus := &instamojo.EventBus{DedupWindow: 50 * time.Millisecond, MaxSeen: 2}
+
+	var got []string
+	bus.Subscribe(func(e instamojo.Event) {
+		got = append(got, e.ID)
+	})
+
+	publish := func(ids ...string) {
+		for _, id := range ids {
+			bus.Publish(instamojo.Event{Type: instamojo.EventPaymentCredited, ID: id, Status: "Credit"})
+		}
+	}
+
+	publish("MOJO1", "MOJO1", "MOJO2")
+	// MOJO1 is forgotten once a third event is remembered
+	publish("MOJO3", "MOJO1", "MOJO3")
+	time.Sleep(60 * time.Millisecond)
+	// And everything is forgotten once the window has passed
+	publish("MOJO3")
+
+	want := "MOJO1 MOJO2 MOJO3 MOJO1 MOJO3"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Got events %v, want %s", got, want)
+	}
+}
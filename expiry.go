@@ -0,0 +1,159 @@
+package instamojo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// ExpiryStore persists the deadlines tracked by an ExpiryScheduler, So they survive restarts
+type ExpiryStore interface {
+	Load() (map[string]time.Time, error)
+	Save(deadlines map[string]time.Time) error
+}
+
+// FileExpiryStore is an ExpiryStore that keeps the deadlines in a JSON file at Path
+type FileExpiryStore struct {
+	Path string
+}
+
+// Load reads the deadlines from the file, A missing file has no deadlines
+func (f *FileExpiryStore) Load() (map[string]time.Time, error) {
+	b, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return map[string]time.Time{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deadlines := map[string]time.Time{}
+	if err := json.Unmarshal(b, &deadlines); err != nil {
+		return nil, err
+	}
+	return deadlines, nil
+}
+
+// Save replaces the file with deadlines, See writeFileAtomic
+func (f *FileExpiryStore) Save(deadlines map[string]time.Time) error {
+	b, err := json.Marshal(deadlines)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.Path, b)
+}
+
+// ExpiryScheduler disables payment requests once their deadline passes.
+// It is meant for API versions that do not support PaymentURLRequest.ExpiresAt.
+// OnError is called, If it is set, When a payment request could not be disabled.
+// Such requests are tried again on the next run, Unless instamojo rejected the request with a BadRequest
+type ExpiryScheduler struct {
+	Config  *Config
+	Store   ExpiryStore
+	OnError func(paymentRequestID string, err error)
+
+	mu        sync.Mutex
+	deadlines map[string]time.Time
+}
+
+// NewExpiryScheduler creates an ExpiryScheduler and loads the deadlines saved in store
+func NewExpiryScheduler(c *Config, store ExpiryStore) (*ExpiryScheduler, error) {
+	deadlines, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExpiryScheduler{Config: c, Store: store, deadlines: deadlines}, nil
+}
+
+// CreatePaymentURL creates a payment request and tracks it until deadline
+func (s *ExpiryScheduler) CreatePaymentURL(p *PaymentURLRequest, deadline time.Time) (*PaymentURLResponse, error) {
+	pr, err := s.Config.CreatePaymentURL(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return pr, s.Track(pr.PaymentRequest.ID, deadline)
+}
+
+// Track disables paymentRequestID once deadline passes
+func (s *ExpiryScheduler) Track(paymentRequestID string, deadline time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deadlines[paymentRequestID] = deadline
+	return s.Store.Save(s.deadlines)
+}
+
+// Untrack stops tracking paymentRequestID, For example because it has been paid
+func (s *ExpiryScheduler) Untrack(paymentRequestID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.deadlines, paymentRequestID)
+	return s.Store.Save(s.deadlines)
+}
+
+// ExpireDue disables all the payment requests whose deadline is before now
+func (s *ExpiryScheduler) ExpireDue(now time.Time) error {
+	s.mu.Lock()
+	var due []string
+	for id, deadline := range s.deadlines {
+		if !deadline.After(now) {
+			due = append(due, id)
+		}
+	}
+	s.mu.Unlock()
+
+	var expired []string
+	for _, id := range due {
+		_, err := s.Config.DisableRequest(id)
+		if err != nil && s.OnError != nil {
+			s.OnError(id, err)
+		}
+
+		var br *BadRequest
+		if err == nil || errors.As(err, &br) {
+			expired = append(expired, id)
+		}
+	}
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range expired {
+		delete(s.deadlines, id)
+	}
+	return s.Store.Save(s.deadlines)
+}
+
+// Run calls ExpireDue every interval until ctx is done
+func (s *ExpiryScheduler) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval: %v", interval)
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := s.ExpireDue(time.Now()); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package instamojo_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestExpiryScheduler(t *testing.T) {
+
+	disabled := map[string]bool{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		disabled[r.URL.Path] = true
+		fmt.Fprint(w, `{"success": true}`)
+	}))
+	defer ts.Close()
+
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &instamojo.FileExpiryStore{Path: filepath.Join(t.TempDir(), "expiry.json")}
+	s, err := instamojo.NewExpiryScheduler(c, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err := s.Track("PR1", now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Track("PR2", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted scheduler picks the deadlines up from the store
+	s, err = instamojo.NewExpiryScheduler(c, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ExpireDue(now); err != nil {
+		t.Fatal(err)
+	}
+
+	if !disabled["/api/1.1/payment-requests/PR1/disable"] || len(disabled) != 1 {
+		t.Errorf("Got %v, want only PR1 disabled", disabled)
+	}
+
+	deadlines, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := deadlines["PR1"]; ok || len(deadlines) != 1 {
+		t.Errorf("Got %v, want only PR2 tracked", deadlines)
+	}
+
+	if err := s.Run(context.Background(), 0); err == nil {
+		t.Error("ran with a zero interval")
+	}
+}
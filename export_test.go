@@ -0,0 +1,10 @@
+package instamojo
+
+// SetVerifyEndpoints points VerifyCredentials at production and sandbox until restore is called
+func SetVerifyEndpoints(production, sandbox string) (restore func()) {
+	old := verifyEndpoints
+	verifyEndpoints.production, verifyEndpoints.sandbox = production, sandbox
+	return func() {
+		verifyEndpoints = old
+	}
+}
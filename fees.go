@@ -0,0 +1,82 @@
+package instamojo
+
+import (
+	"fmt"
+	"math"
+)
+
+// FeeModel is the pricing of an instamojo plan, A percentage of the amount plus a fixed fee,
+// With GST charged on top of the fee. Percent and GST are percentages, So 2 means 2%
+type FeeModel struct {
+	Percent float64
+	Fixed   Amount
+	GST     float64
+}
+
+// StandardFeeModel is the pricing of instamojo's standard plan at the time of writing.
+// Check the pricing of your own plan before relying on it
+var StandardFeeModel = FeeModel{Percent: 2, Fixed: 300, GST: 18}
+
+// Fee predicts the fee, Including GST, that instamojo will charge on a payment of amount.
+// Each component is rounded to the nearest paisa
+func (m FeeModel) Fee(amount Amount) Amount {
+	fee := Amount(math.Round(float64(amount)*m.Percent/100)) + m.Fixed
+	gst := Amount(math.Round(float64(fee) * m.GST / 100))
+	return fee + gst
+}
+
+// Net is what will be settled for a payment of amount after the fee is taken out
+func (m FeeModel) Net(amount Amount) Amount {
+	return amount - m.Fee(amount)
+}
+
+// Settlement adds up the actual amounts and fees of a set of payments.
+// Only credited payments are counted, The zero value is ready to use
+type Settlement struct {
+	Count  int
+	Amount Amount
+	Fees   Amount
+	Net    Amount
+}
+
+// AddPayment adds a payment fetched with PaymentDetails to the settlement
+func (s *Settlement) AddPayment(p *PaymentDetails) error {
+	if p.Payment.Status != "Credit" {
+		return nil
+	}
+	if err := s.add(p.Payment.Amount, p.Payment.Fees); err != nil {
+		return fmt.Errorf("payment %s: %v", p.Payment.PaymentID, err)
+	}
+	return nil
+}
+
+// AddWebhook adds a payment received on the webhook to the settlement
+func (s *Settlement) AddWebhook(w *WebhookResponse) error {
+	if w.Status != "Credit" {
+		return nil
+	}
+	if err := s.add(w.Amount, w.Fees); err != nil {
+		return fmt.Errorf("payment %s: %v", w.PaymentID, err)
+	}
+	return nil
+}
+
+func (s *Settlement) add(amount, fees string) error {
+	a, err := ParseAmount(amount)
+	if err != nil {
+		return err
+	}
+
+	f := Amount(0)
+	if fees != "" {
+		if f, err = ParseAmount(fees); err != nil {
+			return err
+		}
+	}
+
+	s.Count++
+	s.Amount += a
+	s.Fees += f
+	s.Net += a - f
+	return nil
+}
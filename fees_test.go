@@ -0,0 +1,39 @@
+package instamojo_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func ExampleFeeModel_Fee() {
+
+	// 2% of 2500.00 is 50.00, Plus 3.00 fixed and 18% GST on 53.00
+	fmt.Println(instamojo.StandardFeeModel.Fee(250000))
+	fmt.Println(instamojo.StandardFeeModel.Net(250000))
+	// Output:
+	// 62.54
+	// 2437.46
+}
+
+func TestSettlement(t *testing.T) {
+
+	var s instamojo.Settlement
+
+	webhooks := []*instamojo.WebhookResponse{
+		{PaymentID: "MOJO1", Status: "Credit", Amount: "2500.00", Fees: "125.00"},
+		{PaymentID: "MOJO2", Status: "Failed", Amount: "100.00", Fees: "0.00"},
+		{PaymentID: "MOJO3", Status: "Credit", Amount: "10.50", Fees: "0.21"},
+	}
+	for _, w := range webhooks {
+		if err := s.AddWebhook(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := instamojo.Settlement{Count: 2, Amount: 251050, Fees: 12521, Net: 238529}
+	if s != want {
+		t.Errorf("Got %+v, want %+v", s, want)
+	}
+}
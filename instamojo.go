@@ -1,9 +1,11 @@
 // Package instamojo aims to provide a Wrapper for instamojo.com's API
-// It is a work in progress and all remaining endpoints shall be added soon
+// It is a work in progress and all remaining endpoints shall be added soon,
+// Until then Config.Do can call the ones that are not wrapped yet
 package instamojo
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,23 +13,50 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // Init initialises a new Config from the provided settings
 func Init(c *Config) (*Config, error) {
-	if c.APIKey == "" || c.AuthToken == "" {
+	if c.Credentials == nil && (c.APIKey == "" || c.AuthToken == "") {
 		return nil, fmt.Errorf("invalid tokens")
 	}
 
-	if c.SandboxMode {
-		c.endpoint = "https://test.instamojo.com"
-	} else {
-		c.endpoint = "https://www.instamojo.com"
+	if err := c.setEndpoint(); err != nil {
+		return nil, err
+	}
+
+	if c.APIVersion == "" {
+		c.APIVersion = DefaultAPIVersion
 	}
 
+	c.flight = &flightGroup{}
+
 	return c, nil
 }
 
+// setEndpoint picks the URL that requests are sent to, From BaseURL or SandboxMode
+func (c *Config) setEndpoint() error {
+	switch {
+	case c.BaseURL != "":
+		u, err := url.Parse(c.BaseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid base url: %q", c.BaseURL)
+		}
+		c.endpoint = strings.TrimRight(c.BaseURL, "/")
+	case c.SandboxMode:
+		c.endpoint = SandboxURL
+	default:
+		c.endpoint = ProductionURL
+	}
+	return nil
+}
+
+// apiURL returns the absolute URL of an endpoint under the configured API version
+func (c *Config) apiURL(format string, a ...interface{}) string {
+	return fmt.Sprintf("%s/api/%s/%s", c.endpoint, c.APIVersion, fmt.Sprintf(format, a...))
+}
+
 // ParseWebhookResponse parses the urlencoded response that instamojo sends to the webhook
 func ParseWebhookResponse(u url.Values) *WebhookResponse {
 
@@ -48,33 +77,123 @@ func ParseWebhookResponse(u url.Values) *WebhookResponse {
 	}
 }
 
-func (c *Config) makeRequest(m, url string, body io.Reader) (*http.Response, error) {
+func (c *Config) makeRequest(op Operation, m, url string, body []byte) (*http.Response, error) {
+	return c.makeRequestContext(context.Background(), op, m, url, body)
+}
 
-	req, err := http.NewRequest(m, url, body)
-	if err != nil {
-		return nil, err
+// makeRequestContext is makeRequest with a context, It stops retrying once ctx is done
+func (c *Config) makeRequestContext(ctx context.Context, op Operation, m, url string, body []byte) (*http.Response, error) {
+	contentType := ""
+	if m == "POST" || body != nil {
+		contentType = "application/json"
 	}
-	req.Header.Set("X-Api-Key", c.APIKey)
-	req.Header.Set("X-Auth-Token", c.AuthToken)
+	return c.sendRequest(ctx, op, m, url, body, contentType, c.apiKeyAuth())
+}
+
+// requestAuth sets the credentials of the requests sent by sendRequest.
+// refresh, If it is set, Is called once when the credentials are rejected with a 401 and the request is sent again
+type requestAuth struct {
+	set     func(req *http.Request) error
+	refresh func() error
+}
 
-	if m == "POST" {
-		req.Header.Set("Content-Type", "application/json")
+// apiKeyAuth authenticates with the API key and auth token from the credentials of the Config
+func (c *Config) apiKeyAuth() requestAuth {
+	a := requestAuth{
+		set: func(req *http.Request) error {
+			creds, err := c.credentials()
+			if err != nil {
+				return err
+			}
+			req.Header.Set("X-Api-Key", creds.APIKey)
+			req.Header.Set("X-Auth-Token", creds.AuthToken)
+			return nil
+		},
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	// The credentials may have been rotated since they were read
+	if rf, ok := c.Credentials.(CredentialsRefresher); ok {
+		a.refresh = rf.Refresh
+	}
+	return a
+}
+
+// sendRequest sends body through the middleware with the credentials set by auth,
+// Retrying it until it succeeds, c.Retries is reached or ctx is done
+func (c *Config) sendRequest(ctx context.Context, op Operation, m, url string, body []byte, contentType string, auth requestAuth) (*http.Response, error) {
+
+	rt := chain(c.middleware(), c.send)
+	refreshed := false
+
+	for attempt := 0; ; attempt++ {
+		var r io.Reader
+		if body != nil {
+			r = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, m, url, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := auth.set(req); err != nil {
+			return nil, err
+		}
+		if c.UserAgent != "" {
+			req.Header.Set("User-Agent", c.UserAgent)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := rt(op, req)
+
+		if auth.refresh != nil && err == nil && resp.StatusCode == 401 && !refreshed {
+			refreshed = true
+			resp.Body.Close()
+			if err := auth.refresh(); err != nil {
+				return nil, err
+			}
+			attempt--
+			continue
+		}
+
+		if attempt >= c.Retries || !retryable(m, resp, err) {
+			if err != nil {
+				return nil, err
+			}
+			return c.checkResponse(resp)
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		t := time.NewTimer(retryBackoff << uint(attempt))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
 	}
+}
+
+func (c *Config) checkResponse(resp *http.Response) (*http.Response, error) {
 
 	// Handle the irrecoverable errors here
 	switch resp.StatusCode {
 	case 404:
+		resp.Body.Close()
 		return nil, fmt.Errorf("404 Not Found")
 	case 500, 502, 504:
+		resp.Body.Close()
 		return nil, fmt.Errorf("internal server error")
 	case 403:
+		resp.Body.Close()
 		return nil, fmt.Errorf("insufficient permissions")
+	case 429:
+		resp.Body.Close()
+		return nil, ErrTooManyRequests
 	}
 
 	return resp, nil
@@ -89,7 +208,7 @@ func (c *Config) CreatePaymentURL(p *PaymentURLRequest) (*PaymentURLResponse, er
 		return nil, fmt.Errorf("error in marshalling PaymentURLRequest: %v", err)
 	}
 
-	resp, err := c.makeRequest("POST", fmt.Sprintf("%s/api/1.1/payment-requests/", c.endpoint), strings.NewReader(string(b)))
+	resp, err := c.makeRequest(Operation{Name: "CreatePaymentURL"}, "POST", c.apiURL("payment-requests/"), b)
 
 	if err != nil {
 		return nil, err
@@ -104,6 +223,8 @@ func (c *Config) CreatePaymentURL(p *PaymentURLRequest) (*PaymentURLResponse, er
 			return nil, err
 		}
 
+		c.save(KindPaymentRequest, s.PaymentRequest.ID, s)
+		c.publish(Event{Type: EventPaymentRequestCreated, ID: s.PaymentRequest.ID, Status: s.PaymentRequest.Status, Data: s})
 		return s, nil
 	case 400:
 		return nil, badrequest(resp)
@@ -116,8 +237,18 @@ func (c *Config) CreatePaymentURL(p *PaymentURLRequest) (*PaymentURLResponse, er
 
 //ListRequests returns a array of all the lists created so far
 func (c *Config) ListRequests() (*RequestsList, error) {
+	return c.ListRequestsWithOptions(nil)
+}
 
-	resp, err := c.makeRequest("GET", fmt.Sprintf("%s/api/1.1/payment-requests/", c.endpoint), nil)
+// ListRequestsWithOptions is ListRequests with filters on the created and modified times, And pagination
+func (c *Config) ListRequestsWithOptions(o *ListRequestsOptions) (*RequestsList, error) {
+
+	u := c.apiURL("payment-requests/")
+	if q := o.query().Encode(); q != "" {
+		u += "?" + q
+	}
+
+	resp, err := c.makeRequest(Operation{Name: "ListRequests"}, "GET", u, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -145,7 +276,7 @@ func (c *Config) ListRequests() (*RequestsList, error) {
 // PaymentRequestDetails fetches details about a payment request ID
 func (c *Config) PaymentRequestDetails(id string) (*PaymentRequestDetails, error) {
 
-	resp, err := c.makeRequest("GET", fmt.Sprintf("%s/api/1.1/payment-requests/%s", c.endpoint, id), nil)
+	resp, err := c.makeRequest(Operation{Name: "PaymentRequestDetails", ID: id}, "GET", c.apiURL("payment-requests/%s", id), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -172,12 +303,11 @@ func (c *Config) PaymentRequestDetails(id string) (*PaymentRequestDetails, error
 
 // CreateRefundRequest creates a refund request
 func (c *Config) CreateRefundRequest(r *CreateRefundRequest) (*CreateRefundResponse, error) {
-	var buf bytes.Buffer
-	err := json.NewDecoder(&buf).Decode(r)
+	b, err := json.Marshal(r)
 	if err != nil {
-		return nil, fmt.Errorf("Error in decoding CreateRefundRequest")
+		return nil, fmt.Errorf("error in marshalling CreateRefundRequest: %v", err)
 	}
-	resp, err := c.makeRequest("POST", fmt.Sprintf("%s/api/1.1/refunds", c.endpoint), strings.NewReader(string(buf.Bytes())))
+	resp, err := c.makeRequest(Operation{Name: "CreateRefundRequest", ID: r.PaymentID}, "POST", c.apiURL("refunds"), b)
 	if err != nil {
 		return nil, err
 	}
@@ -191,6 +321,7 @@ func (c *Config) CreateRefundRequest(r *CreateRefundRequest) (*CreateRefundRespo
 			return nil, err
 		}
 
+		c.publish(Event{Type: EventRefundCreated, ID: crr.Refund.ID, Status: crr.Refund.Status, Data: crr})
 		return crr, nil
 	case 400:
 		return nil, badrequest(resp)
@@ -203,7 +334,7 @@ func (c *Config) CreateRefundRequest(r *CreateRefundRequest) (*CreateRefundRespo
 
 // ListRefunds returns a list of all the refunds made so far
 func (c *Config) ListRefunds() (*RefundsList, error) {
-	resp, err := c.makeRequest("GET", fmt.Sprintf("%s/api/1.1/refunds", c.endpoint), nil)
+	resp, err := c.makeRequest(Operation{Name: "ListRefunds"}, "GET", c.apiURL("refunds"), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -228,8 +359,28 @@ func (c *Config) ListRefunds() (*RefundsList, error) {
 }
 
 // RefundDetails can be used to retrieve details about a refund
+// If Config.Cache is set, The response is looked up in the cache first
 func (c *Config) RefundDetails(refundID string) (*RefundDetails, error) {
-	resp, err := c.makeRequest("GET", fmt.Sprintf("%s/api/1.1/refunds/%s", c.endpoint, refundID), nil)
+	if c.Cache == nil {
+		return c.refundDetails(refundID)
+	}
+
+	rd := &RefundDetails{}
+	err := c.cached("refunds/"+refundID, rd, func() (interface{}, bool, error) {
+		rd, err := c.refundDetails(refundID)
+		if err != nil {
+			return nil, false, err
+		}
+		return rd, refundFinal(rd.Refund.Status), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+func (c *Config) refundDetails(refundID string) (*RefundDetails, error) {
+	resp, err := c.makeRequest(Operation{Name: "RefundDetails", ID: refundID}, "GET", c.apiURL("refunds/%s", refundID), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -242,6 +393,8 @@ func (c *Config) RefundDetails(refundID string) (*RefundDetails, error) {
 		if err != nil {
 			return nil, err
 		}
+
+		c.save(KindRefund, rd.Refund.ID, rd)
 		return rd, nil
 	case 400:
 		return nil, badrequest(resp)
@@ -255,9 +408,29 @@ func (c *Config) RefundDetails(refundID string) (*RefundDetails, error) {
 // PaymentDetails is used to fetch details about a payment
 // The difference b/w this and PaymentRequestDetails is that PaymentDetails is used to fetch details about successful payments
 // And PaymentRequestDetails is used to fetch details about a payment id
+// If Config.Cache is set, The response is looked up in the cache first
 func (c *Config) PaymentDetails(paymentID string) (*PaymentDetails, error) {
+	if c.Cache == nil {
+		return c.paymentDetails(paymentID)
+	}
 
-	resp, err := c.makeRequest("GET", fmt.Sprintf("%s/api/1.1/payments/%s", c.endpoint, paymentID), nil)
+	pd := &PaymentDetails{}
+	err := c.cached("payments/"+paymentID, pd, func() (interface{}, bool, error) {
+		pd, err := c.paymentDetails(paymentID)
+		if err != nil {
+			return nil, false, err
+		}
+		return pd, paymentFinal(pd.Payment.Status), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pd, nil
+}
+
+func (c *Config) paymentDetails(paymentID string) (*PaymentDetails, error) {
+
+	resp, err := c.makeRequest(Operation{Name: "PaymentDetails", ID: paymentID}, "GET", c.apiURL("payments/%s", paymentID), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -271,6 +444,7 @@ func (c *Config) PaymentDetails(paymentID string) (*PaymentDetails, error) {
 			return nil, err
 		}
 
+		c.save(KindPayment, pd.Payment.PaymentID, pd)
 		return pd, nil
 	case 400:
 		return nil, badrequest(resp)
@@ -282,9 +456,9 @@ func (c *Config) PaymentDetails(paymentID string) (*PaymentDetails, error) {
 }
 
 // DisableRequest disables a Payment Request
-func (c *Config) DisableRequest(paymentRequestID string) (*successResponse, error) {
+func (c *Config) DisableRequest(paymentRequestID string) (*SuccessResponse, error) {
 
-	resp, err := c.makeRequest("POST", fmt.Sprintf("%s/api/1.1/payment-requests/%s/disable", c.endpoint, paymentRequestID), nil)
+	resp, err := c.makeRequest(Operation{Name: "DisableRequest", ID: paymentRequestID}, "POST", c.apiURL("payment-requests/%s/disable", paymentRequestID), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -292,7 +466,7 @@ func (c *Config) DisableRequest(paymentRequestID string) (*successResponse, erro
 
 	switch resp.StatusCode {
 	case 200:
-		sr := &successResponse{}
+		sr := &SuccessResponse{}
 		err := json.NewDecoder(resp.Body).Decode(sr)
 		if err != nil {
 			return nil, err
@@ -309,9 +483,9 @@ func (c *Config) DisableRequest(paymentRequestID string) (*successResponse, erro
 }
 
 // EnableRequest enables a Payment Request
-func (c *Config) EnableRequest(paymentRequestID string) (*successResponse, error) {
+func (c *Config) EnableRequest(paymentRequestID string) (*SuccessResponse, error) {
 
-	resp, err := c.makeRequest("POST", fmt.Sprintf("%s/api/1.1/payment-requests/%s/enable", c.endpoint, paymentRequestID), nil)
+	resp, err := c.makeRequest(Operation{Name: "EnableRequest", ID: paymentRequestID}, "POST", c.apiURL("payment-requests/%s/enable", paymentRequestID), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -319,7 +493,7 @@ func (c *Config) EnableRequest(paymentRequestID string) (*successResponse, error
 
 	switch resp.StatusCode {
 	case 200:
-		sr := &successResponse{}
+		sr := &SuccessResponse{}
 		err := json.NewDecoder(resp.Body).Decode(sr)
 		if err != nil {
 			return nil, err
@@ -335,6 +509,83 @@ func (c *Config) EnableRequest(paymentRequestID string) (*successResponse, error
 	return nil, defaultResponse(resp)
 }
 
+// Do calls any endpoint of the API with the same credentials, base URL, retries and error handling as the
+// methods of Config, For the endpoints that are not wrapped yet. path is relative to the API version,
+// Like "payments/MOJO1/" or "payment-requests/?page=2", Unless it starts with "/" in which case it is relative
+// to the endpoint, Like "/v2/payouts/". It can be an absolute URL too, As long as it is on the host of the endpoint
+// so that the credentials are never sent anywhere else. body is sent as it is if it is a []byte and JSON encoded
+// otherwise, Or not at all if it is nil. A successful response is decoded into out unless out is nil.
+// 400 and 401 responses return a *BadRequest and an *Unauthorized
+func (c *Config) Do(ctx context.Context, method, path string, body, out interface{}) error {
+
+	u, err := c.resolve(path)
+	if err != nil {
+		return err
+	}
+
+	var b []byte
+	switch v := body.(type) {
+	case nil:
+	case []byte:
+		b = v
+	default:
+		b, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error in marshalling request body: %v", err)
+		}
+	}
+
+	op := Operation{Name: "Do", Method: method, Path: u.Path}
+	resp, err := c.makeRequestContext(ctx, op, method, u.String(), b)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		if out == nil || resp.StatusCode == 204 {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	case resp.StatusCode == 400:
+		return badrequest(resp)
+	case resp.StatusCode == 401:
+		return unauthorized(resp)
+	}
+
+	return defaultResponse(resp)
+}
+
+// resolve turns the path given to Do into an absolute URL on the endpoint
+func (c *Config) resolve(path string) (*url.URL, error) {
+	endpoint, err := url.Parse(c.endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %v", path, err)
+	}
+
+	switch {
+	case u.IsAbs():
+	case strings.HasPrefix(path, "/"):
+		u, err = url.Parse(c.endpoint + path)
+	default:
+		u, err = url.Parse(c.apiURL("%s", path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %v", path, err)
+	}
+
+	if u.Scheme != endpoint.Scheme || u.Host != endpoint.Host {
+		return nil, fmt.Errorf("url %q is not on the endpoint %s", path, c.endpoint)
+	}
+	return u, nil
+}
+
 func badrequest(resp *http.Response) error {
 	br := &BadRequest{}
 	err := json.NewDecoder(resp.Body).Decode(br)
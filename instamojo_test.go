@@ -1,8 +1,14 @@
 package instamojo_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/ishanjain28/instamojo"
@@ -68,3 +74,125 @@ func TestParseWebhookResponse(t *testing.T) {
 		t.Errorf("Got %q, want %q", got, want)
 	}
 }
+
+func TestBaseURL(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/2.0/payments/MOJO5a06005J21512197" {
+			t.Errorf("Got path %q", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO5a06005J21512197", "status": "Credit"}}`)
+	}))
+	defer ts.Close()
+
+	c, err := instamojo.Init(&instamojo.Config{
+		APIKey:     "key",
+		AuthToken:  "token",
+		BaseURL:    ts.URL + "/",
+		APIVersion: "2.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pd, err := c.PaymentDetails("MOJO5a06005J21512197")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pd.Payment.Status != "Credit" {
+		t.Errorf("Got status %q, want %q", pd.Payment.Status, "Credit")
+	}
+}
+
+func TestDo(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "key" || r.Header.Get("X-Auth-Token") != "token" {
+			w.WriteHeader(401)
+			fmt.Fprint(w, `{"success": false, "message": "Invalid token."}`)
+			return
+		}
+
+		switch r.URL.RequestURI() {
+		case "/api/1.1/payment-requests/MOJO1/":
+			if r.Method != "PATCH" || r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Got %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+			}
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["purpose"] != "Renamed" {
+				t.Errorf("Got body %v, error %v", body, err)
+			}
+			fmt.Fprint(w, `{"success": true, "payment_request": {"id": "MOJO1", "purpose": "Renamed"}}`)
+		case "/v2/payouts/":
+			if op := r.Header.Get("X-Operation"); op != "Do GET /v2/payouts/" {
+				t.Errorf("Got operation %q", op)
+			}
+			fmt.Fprint(w, `{"count": 0}`)
+		case "/api/1.1/payment-requests/?redirect_url=https://shop.example/x":
+			fmt.Fprint(w, `{"success": true, "payment_requests": []}`)
+		case "/api/1.1/payouts/?page=2":
+			w.WriteHeader(400)
+			fmt.Fprint(w, `{"success": false, "message": {"page": ["Invalid page."]}}`)
+		default:
+			w.WriteHeader(418)
+			fmt.Fprint(w, "teapot")
+		}
+	}))
+	defer ts.Close()
+
+	// The operation is sent to the server so that it can be checked
+	operation := func(next instamojo.RoundTripFunc) instamojo.RoundTripFunc {
+		return func(op instamojo.Operation, req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Operation", op.Name+" "+op.Method+" "+op.Path)
+			return next(op, req)
+		}
+	}
+
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: ts.URL, Middleware: []instamojo.Middleware{operation}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	prd := &instamojo.PaymentRequestDetails{}
+	if err := c.Do(ctx, "PATCH", "payment-requests/MOJO1/", map[string]string{"purpose": "Renamed"}, prd); err != nil {
+		t.Fatal(err)
+	}
+	if prd.PaymentRequest.Purpose != "Renamed" {
+		t.Errorf("Got %+v", prd)
+	}
+
+	err = c.Do(ctx, "GET", "payouts/?page=2", nil, nil)
+	var br *instamojo.BadRequest
+	if !errors.As(err, &br) || err.Error() != "Invalid page." {
+		t.Errorf("Got error %v", err)
+	}
+
+	// Paths that start with a slash and absolute URLs on the same host are relative to the endpoint
+	var payouts map[string]int
+	for _, path := range []string{"/v2/payouts/", ts.URL + "/v2/payouts/"} {
+		if err := c.Do(ctx, "GET", path, nil, &payouts); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+	// A URL in the query does not make the path absolute
+	if err := c.Do(ctx, "GET", "payment-requests/?redirect_url=https://shop.example/x", nil, nil); err != nil {
+		t.Error(err)
+	}
+	if err := c.Do(ctx, "GET", "https://example.com/v2/payouts/", nil, nil); err == nil {
+		t.Error("sent the credentials to another host")
+	}
+
+	if err := c.Do(ctx, "GET", "unknown/", nil, nil); err == nil || !strings.Contains(err.Error(), "teapot") {
+		t.Errorf("Got error %v", err)
+	}
+
+	bad, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "wrong", BaseURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ua *instamojo.Unauthorized
+	if err := bad.Do(ctx, "GET", "payouts/", nil, nil); !errors.As(err, &ua) {
+		t.Errorf("Got error %v", err)
+	}
+}
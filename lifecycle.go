@@ -0,0 +1,138 @@
+package instamojo
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// RequestState is the status of a payment request
+type RequestState string
+
+// The states that a payment request goes through
+const (
+	StatePending   RequestState = "Pending"
+	StateSent      RequestState = "Sent"
+	StateCompleted RequestState = "Completed"
+	StateDisabled  RequestState = "Disabled"
+	StateExpired   RequestState = "Expired"
+)
+
+// ErrInvalidTransition is returned when a payment request can not move between two states
+var ErrInvalidTransition = errors.New("instamojo: invalid payment request transition")
+
+// transitions has the states that a payment request can move to from each state.
+// A completed request can still be disabled, Because it may allow repeated payments
+var transitions = map[RequestState][]RequestState{
+	StatePending:   {StateSent, StateCompleted, StateDisabled, StateExpired},
+	StateSent:      {StateCompleted, StateDisabled, StateExpired},
+	StateCompleted: {StateDisabled},
+	StateDisabled:  {StatePending, StateSent, StateCompleted},
+	StateExpired:   nil,
+}
+
+// CanTransition reports whether a payment request in state s can move to state to
+func (s RequestState) CanTransition(to RequestState) bool {
+	for _, t := range transitions[s] {
+		if t == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionEvent is emitted by a Lifecycle when a payment request changes state
+type TransitionEvent struct {
+	PaymentRequestID string
+	From             RequestState
+	To               RequestState
+	Details          *PaymentRequestDetails
+}
+
+// Lifecycle keeps track of the state of payment requests and emits a TransitionEvent
+// to its subscribers every time one of them changes
+type Lifecycle struct {
+	Config *Config
+
+	mu          sync.Mutex
+	states      map[string]RequestState
+	subscribers []func(TransitionEvent)
+}
+
+// NewLifecycle creates a Lifecycle for the payment requests of c
+func NewLifecycle(c *Config) *Lifecycle {
+	return &Lifecycle{Config: c, states: make(map[string]RequestState)}
+}
+
+// Subscribe calls fn with every TransitionEvent, In the order they happen
+func (l *Lifecycle) Subscribe(fn func(TransitionEvent)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.subscribers = append(l.subscribers, fn)
+}
+
+// Refresh fetches a payment request and emits a TransitionEvent if its state is not the one seen last time.
+// The first time a payment request is seen, The event has an empty From
+func (l *Lifecycle) Refresh(paymentRequestID string) (*PaymentRequestDetails, error) {
+	prd, err := l.Config.PaymentRequestDetails(paymentRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	l.observe(paymentRequestID, prd)
+	return prd, nil
+}
+
+// Enable enables a disabled payment request and returns its refreshed details
+func (l *Lifecycle) Enable(paymentRequestID string) (*PaymentRequestDetails, error) {
+	return l.transition(paymentRequestID, StatePending, l.Config.EnableRequest)
+}
+
+// Disable disables a payment request and returns its refreshed details
+func (l *Lifecycle) Disable(paymentRequestID string) (*PaymentRequestDetails, error) {
+	return l.transition(paymentRequestID, StateDisabled, l.Config.DisableRequest)
+}
+
+// transition checks that the payment request can move to the state to, Calls fn
+// and then refreshes the payment request to find out the state it actually moved to
+func (l *Lifecycle) transition(paymentRequestID string, to RequestState, fn func(string) (*SuccessResponse, error)) (*PaymentRequestDetails, error) {
+	prd, err := l.Refresh(paymentRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	from := RequestState(prd.PaymentRequest.Status)
+	if !from.CanTransition(to) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+
+	sr, err := fn(paymentRequestID)
+	if err != nil {
+		return nil, err
+	}
+	if !sr.Success {
+		return nil, fmt.Errorf("instamojo: could not move payment request %s to %s", paymentRequestID, to)
+	}
+
+	return l.Refresh(paymentRequestID)
+}
+
+func (l *Lifecycle) observe(paymentRequestID string, prd *PaymentRequestDetails) {
+	to := RequestState(prd.PaymentRequest.Status)
+
+	l.mu.Lock()
+	from, seen := l.states[paymentRequestID]
+	if seen && from == to {
+		l.mu.Unlock()
+		return
+	}
+	l.states[paymentRequestID] = to
+	subscribers := l.subscribers
+	l.mu.Unlock()
+
+	e := TransitionEvent{PaymentRequestID: paymentRequestID, From: from, To: to, Details: prd}
+	for _, fn := range subscribers {
+		fn(e)
+	}
+}
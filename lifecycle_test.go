@@ -0,0 +1,62 @@
+package instamojo_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestLifecycle(t *testing.T) {
+
+	status := "Pending"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/disable"):
+			status = "Disabled"
+		case strings.HasSuffix(r.URL.Path, "/enable"):
+			status = "Pending"
+		default:
+			fmt.Fprintf(w, `{"success": true, "payment_request": {"id": "PR1", "status": %q}}`, status)
+			return
+		}
+		fmt.Fprint(w, `{"success": true}`)
+	}))
+	defer ts.Close()
+
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	l := instamojo.NewLifecycle(c)
+	l.Subscribe(func(e instamojo.TransitionEvent) {
+		events = append(events, fmt.Sprintf("%s->%s", e.From, e.To))
+	})
+
+	prd, err := l.Disable("PR1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prd.PaymentRequest.Status != "Disabled" {
+		t.Errorf("Got status %q, want Disabled", prd.PaymentRequest.Status)
+	}
+
+	if _, err := l.Disable("PR1"); !errors.Is(err, instamojo.ErrInvalidTransition) {
+		t.Errorf("Got %v, want %v", err, instamojo.ErrInvalidTransition)
+	}
+
+	if _, err := l.Enable("PR1"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "->Pending Pending->Disabled Disabled->Pending"
+	if got := strings.Join(events, " "); got != want {
+		t.Errorf("Got events %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+package instamojo
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// LoggingMiddleware logs every request with its operation, endpoint, status, latency and request id.
+// Requests that succeed are logged at Debug and the rest at Warn, Headers are logged with the
+// API key, auth token and bearer tokens redacted. Setting Config.Logger adds it to a Config
+func LoggingMiddleware(l *slog.Logger) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(op Operation, req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(op, req)
+
+			attrs := []slog.Attr{
+				slog.String("operation", op.Name),
+				slog.String("method", req.Method),
+				slog.String("endpoint", req.URL.Path),
+				slog.Duration("latency", time.Since(start)),
+				slog.Any("headers", redactHeaders(req.Header)),
+			}
+			if op.ID != "" {
+				attrs = append(attrs, slog.String("id", op.ID))
+			}
+
+			level := slog.LevelDebug
+			switch {
+			case err != nil:
+				level = slog.LevelWarn
+				attrs = append(attrs, slog.String("error", err.Error()))
+			default:
+				if resp.StatusCode >= 400 {
+					level = slog.LevelWarn
+				}
+				attrs = append(attrs, slog.Int("status", resp.StatusCode))
+				if id := resp.Header.Get("X-Request-Id"); id != "" {
+					attrs = append(attrs, slog.String("request_id", id))
+				}
+			}
+
+			l.LogAttrs(req.Context(), level, "instamojo request", attrs...)
+			return resp, err
+		}
+	}
+}
+
+// middleware is c.Middleware with the built in middleware that the Config is set up for in front of it
+func (c *Config) middleware() []Middleware {
+	var m []Middleware
+	if c.Logger != nil {
+		m = append(m, LoggingMiddleware(c.Logger))
+	}
+	if c.Metrics != nil {
+		m = append(m, c.Metrics.Middleware())
+	}
+	return append(m, c.Middleware...)
+}
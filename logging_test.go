@@ -0,0 +1,80 @@
+package instamojo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestLogger(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO1", "status": "Credit", "buyer_email": "abc@xyz.com"}}`)
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "secret-key", AuthToken: "secret-token", BaseURL: ts.URL, Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pd, err := c.PaymentDetails("MOJO1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("payment", "details", pd)
+
+	out := buf.String()
+	for _, s := range []string{`"operation":"PaymentDetails"`, `"status":200`, `"request_id":"req-123"`, `"id":"MOJO1"`} {
+		if !strings.Contains(out, s) {
+			t.Errorf("log has no %s: %s", s, out)
+		}
+	}
+	for _, s := range []string{"secret-key", "secret-token", "abc@xyz.com"} {
+		if strings.Contains(out, s) {
+			t.Errorf("log has %s: %s", s, out)
+		}
+	}
+}
+
+func TestWebhookResponseRedaction(t *testing.T) {
+
+	list := &instamojo.RequestsList{}
+	if err := json.Unmarshal([]byte(`{"payment_requests": [{"id": "PR1", "email": "abc@xyz.com", "phone": "9999912345"}]}`), list); err != nil {
+		t.Fatal(err)
+	}
+
+	models := []interface{}{
+		&instamojo.WebhookResponse{PaymentID: "MOJO1", Buyer: "abc@xyz.com", BuyerPhone: "9999912345"},
+		list,
+		&instamojo.User{ID: "u1", Email: "abc@xyz.com", Phone: "9999912345"},
+	}
+
+	for _, m := range models {
+		for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+			got := fmt.Sprintf(format, m)
+			if strings.Contains(got, "abc@xyz.com") || strings.Contains(got, "9999912345") {
+				t.Errorf("%T %s: Got %s", m, format, got)
+			}
+			if !strings.Contains(got, "a**@xyz.com") || !strings.Contains(got, "******2345") {
+				t.Errorf("%T %s: Got %s, want the redacted email and phone", m, format, got)
+			}
+		}
+	}
+
+	// The list that was printed is not changed
+	if list.PaymentRequests[0].Email != "abc@xyz.com" {
+		t.Errorf("Got email %q after printing", list.PaymentRequests[0].Email)
+	}
+}
@@ -0,0 +1,159 @@
+package instamojo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics collects counters about the requests sent by a Config and the webhooks it receives,
+// And serves them in the Prometheus text format. Set Config.Metrics to collect them
+type Metrics struct {
+	mu           sync.Mutex
+	requests     map[requestLabels]int64
+	latencySum   map[string]float64
+	latencyCount map[string]int64
+	webhooks     map[string]int64
+	macFailures  int64
+	credited     Amount
+	fees         Amount
+}
+
+type requestLabels struct {
+	operation string
+	status    string
+}
+
+// NewMetrics creates a Metrics with all the counters at zero
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:     make(map[requestLabels]int64),
+		latencySum:   make(map[string]float64),
+		latencyCount: make(map[string]int64),
+		webhooks:     make(map[string]int64),
+	}
+}
+
+// Middleware counts requests by operation and status code, And records their latency.
+// Requests that fail without a response are counted with the status "error"
+func (m *Metrics) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(op Operation, req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(op, req)
+			elapsed := time.Since(start).Seconds()
+
+			status := "error"
+			if err == nil {
+				status = strconv.Itoa(resp.StatusCode)
+			}
+
+			m.mu.Lock()
+			m.requests[requestLabels{op.Name, status}]++
+			m.latencySum[op.Name] += elapsed
+			m.latencyCount[op.Name]++
+			m.mu.Unlock()
+
+			return resp, err
+		}
+	}
+}
+
+// ObserveWebhook counts a webhook by its status, And adds the amount and fees of credited payments
+func (m *Metrics) ObserveWebhook(w *WebhookResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.webhooks[w.Status]++
+	if w.Status != "Credit" {
+		return
+	}
+	if a, err := ParseAmount(w.Amount); err == nil {
+		m.credited += a
+	}
+	if f, err := ParseAmount(w.Fees); err == nil {
+		m.fees += f
+	}
+}
+
+// ObserveMACFailure counts a webhook that failed mac verification
+func (m *Metrics) ObserveMACFailure() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.macFailures++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+// They are rendered under the lock and written after it is released, So a slow scrape does not hold up requests
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b := &bytes.Buffer{}
+	m.render(b)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(b.Bytes())
+}
+
+// render writes a snapshot of the metrics to w
+func (m *Metrics) render(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeHeader(w, "instamojo_api_requests_total", "counter", "Requests sent to instamojo by operation and status.")
+	requests := make([]requestLabels, 0, len(m.requests))
+	for l := range m.requests {
+		requests = append(requests, l)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].operation != requests[j].operation {
+			return requests[i].operation < requests[j].operation
+		}
+		return requests[i].status < requests[j].status
+	})
+	for _, l := range requests {
+		fmt.Fprintf(w, "instamojo_api_requests_total{operation=%s,status=%s} %d\n", labelValue(l.operation), labelValue(l.status), m.requests[l])
+	}
+
+	writeHeader(w, "instamojo_api_request_duration_seconds", "summary", "Latency of the requests sent to instamojo by operation.")
+	for _, op := range sortedKeys(m.latencyCount) {
+		fmt.Fprintf(w, "instamojo_api_request_duration_seconds_sum{operation=%s} %g\n", labelValue(op), m.latencySum[op])
+		fmt.Fprintf(w, "instamojo_api_request_duration_seconds_count{operation=%s} %d\n", labelValue(op), m.latencyCount[op])
+	}
+
+	writeHeader(w, "instamojo_webhooks_total", "counter", "Webhooks received by payment status.")
+	for _, status := range sortedKeys(m.webhooks) {
+		fmt.Fprintf(w, "instamojo_webhooks_total{status=%s} %d\n", labelValue(status), m.webhooks[status])
+	}
+
+	writeHeader(w, "instamojo_webhook_mac_failures_total", "counter", "Webhooks that failed mac verification.")
+	fmt.Fprintf(w, "instamojo_webhook_mac_failures_total %d\n", m.macFailures)
+
+	writeHeader(w, "instamojo_credited_amount_rupees_total", "counter", "Amount of the credited payments received on webhooks.")
+	fmt.Fprintf(w, "instamojo_credited_amount_rupees_total %s\n", m.credited)
+
+	writeHeader(w, "instamojo_fees_rupees_total", "counter", "Fees of the credited payments received on webhooks.")
+	fmt.Fprintf(w, "instamojo_fees_rupees_total %s\n", m.fees)
+}
+
+func writeHeader(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// labelValue quotes a label value the way the Prometheus text format expects
+func labelValue(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
+
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,78 @@
+package instamojo_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestMetrics(t *testing.T) {
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO1", "status": "Credit"}}`)
+	}))
+	defer api.Close()
+
+	m := instamojo.NewMetrics()
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: api.URL, Metrics: m})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.PaymentDetails("MOJO1"); err != nil {
+		t.Fatal(err)
+	}
+
+	received := 0
+	hook := httptest.NewServer(c.WebhookHandler("salt", func(*instamojo.WebhookResponse) {
+		received++
+	}))
+	defer hook.Close()
+
+	webhooks := []*instamojo.WebhookResponse{
+		{PaymentID: "MOJO1", Status: "Credit", Amount: "2500.00", Fees: "125.00"},
+		{PaymentID: "MOJO2", Status: "Credit", Amount: "10.50", Fees: "0.21"},
+		{PaymentID: "MOJO3", Status: "Failed", Amount: "100.00", Fees: "0.00"},
+	}
+	for _, w := range webhooks {
+		resp, err := instamojo.PostWebhook(hook.URL, w, "salt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// A forged webhook
+	resp, err := http.PostForm(hook.URL, url.Values{"payment_id": []string{"MOJO4"}, "status": []string{"Credit"}, "mac": []string{"forged"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 400 || received != 3 {
+		t.Errorf("Got status %d and %d webhooks, want 400 and 3", resp.StatusCode, received)
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+
+	for _, line := range []string{
+		`instamojo_api_requests_total{operation="PaymentDetails",status="200"} 1`,
+		`instamojo_api_request_duration_seconds_count{operation="PaymentDetails"} 1`,
+		`instamojo_webhooks_total{status="Credit"} 2`,
+		`instamojo_webhooks_total{status="Failed"} 1`,
+		`instamojo_webhook_mac_failures_total 1`,
+		`instamojo_credited_amount_rupees_total 2510.50`,
+		`instamojo_fees_rupees_total 125.21`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("metrics have no %s:\n%s", line, body)
+		}
+	}
+}
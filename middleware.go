@@ -0,0 +1,65 @@
+package instamojo
+
+import (
+	"net/http"
+	"time"
+)
+
+// Operation describes the API call that a request is sent for.
+// Name is the name of the method, Like "CreatePaymentURL", And ID is the id of the
+// payment request, payment or refund that the call is about, If there is one.
+// Method and Path are only set by Config.Do, Path is the path of the URL it was called with
+type Operation struct {
+	Name   string
+	ID     string
+	Method string
+	Path   string
+}
+
+// RoundTripFunc sends a request to instamojo and returns its response
+type RoundTripFunc func(op Operation, req *http.Request) (*http.Response, error)
+
+// Middleware wraps a RoundTripFunc, It can look at or change the request before calling next
+// and the response after it, Or return a response of its own without calling next at all
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// chain wraps rt in all the middleware, So the first middleware is the outermost
+func chain(middleware []Middleware, rt RoundTripFunc) RoundTripFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		rt = middleware[i](rt)
+	}
+	return rt
+}
+
+// send is the RoundTripFunc at the end of every chain, It sends the request over the network
+func (c *Config) send(op Operation, req *http.Request) (*http.Response, error) {
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// retryBackoff is how long makeRequest waits before the first retry, It doubles after every retry
+var retryBackoff = 200 * time.Millisecond
+
+// retryable reports whether a request can be sent again after it got resp or err.
+// Requests that change something are only retried when instamojo throttled them,
+// Because in every other case they may have gone through
+func retryable(method string, resp *http.Response, err error) bool {
+	if err == nil && resp.StatusCode == 429 {
+		return true
+	}
+	if method != "GET" {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case 500, 502, 503, 504:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package instamojo_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestMiddleware(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace") != "abc" {
+			t.Errorf("Got X-Trace %q, want abc", r.Header.Get("X-Trace"))
+		}
+		fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO1", "status": "Credit"}}`)
+	}))
+	defer ts.Close()
+
+	var calls []string
+	logger := func(next instamojo.RoundTripFunc) instamojo.RoundTripFunc {
+		return func(op instamojo.Operation, req *http.Request) (*http.Response, error) {
+			resp, err := next(op, req)
+			if err == nil {
+				calls = append(calls, fmt.Sprintf("%s %s %d", op.Name, op.ID, resp.StatusCode))
+			}
+			return resp, err
+		}
+	}
+	headers := func(next instamojo.RoundTripFunc) instamojo.RoundTripFunc {
+		return func(op instamojo.Operation, req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Trace", "abc")
+			return next(op, req)
+		}
+	}
+	// Fails refunds without sending them
+	faults := func(next instamojo.RoundTripFunc) instamojo.RoundTripFunc {
+		return func(op instamojo.Operation, req *http.Request) (*http.Response, error) {
+			if op.Name != "RefundDetails" {
+				return next(op, req)
+			}
+			return &http.Response{
+				StatusCode: 401,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"success": false, "message": "injected"}`)),
+			}, nil
+		}
+	}
+
+	c, err := instamojo.Init(&instamojo.Config{
+		APIKey:     "key",
+		AuthToken:  "token",
+		BaseURL:    ts.URL,
+		Middleware: []instamojo.Middleware{logger, headers, faults},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.PaymentDetails("MOJO1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RefundDetails("C1"); err == nil || err.Error() != "injected" {
+		t.Errorf("Got %v, want injected", err)
+	}
+
+	want := "PaymentDetails MOJO1 200,RefundDetails C1 401"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
@@ -1,6 +1,10 @@
 package instamojo
 
 import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -27,51 +31,105 @@ func (b BadRequest) Error() string {
 	return "instamojo: bad request"
 }
 
+// Endpoints and API version used when Config does not override them
+const (
+	ProductionURL     = "https://www.instamojo.com"
+	SandboxURL        = "https://test.instamojo.com"
+	DefaultAPIVersion = "1.1"
+
+	// ProductionAPIURL is the endpoint of the v2 API in production, The sandbox serves both APIs from SandboxURL
+	ProductionAPIURL = "https://api.instamojo.com"
+)
+
 // Config is the configuration struct that is used in initialising the package
+// BaseURL overrides the endpoint picked by SandboxMode, which is useful for pointing
+// the package at a local fake server or a reverse proxy. APIVersion defaults to DefaultAPIVersion
+//
+// Cache is optional, When it is set PaymentDetails and RefundDetails are cached in it.
+// Payments and refunds in a final state are cached forever, Others are cached for CacheTTL
+// or not at all if CacheTTL is zero. Entries are keyed by the endpoint and API key too,
+// So one Cache can be shared by the Configs of several accounts
+//
+// Store is optional too, When it is set every payment request, payment, refund and webhook
+// that goes through the Config is saved in it. Errors in saving them are passed to OnStoreError
+//
+// Events is optional as well, When it is set the payment requests and refunds created with the Config
+// and the webhooks parsed by it are published on it
+//
+// Middleware wraps every request that the Config sends, The first one is the outermost.
+// If Logger is set, Every request is logged to it with LoggingMiddleware.
+// If Metrics is set, Every request and every webhook received by WebhookHandler is counted in it
+//
+// HTTPClient defaults to http.DefaultClient. Retries is the number of times a request is sent again
+// after it failed, Only requests that can not have gone through are retried
+//
+// Credentials, If it is set, Is asked for the API key and auth token on every request
+// and APIKey and AuthToken are not used
+//
+// PrivateSalt is the salt that webhooks are signed with, WebhookHandler uses it when it is given no salt
 type Config struct {
-	APIKey      string
-	AuthToken   string
-	SandboxMode bool
-	endpoint    string
+	APIKey       string
+	AuthToken    string
+	SandboxMode  bool
+	BaseURL      string
+	APIVersion   string
+	Cache        Cache
+	CacheTTL     time.Duration
+	Store        Store
+	OnStoreError func(err error)
+	Events       *EventBus
+	Middleware   []Middleware
+	Logger       *slog.Logger
+	Metrics      *Metrics
+	HTTPClient   *http.Client
+	UserAgent    string
+	Retries      int
+	Credentials  CredentialsProvider
+	PrivateSalt  string
+	endpoint     string
+	flight       *flightGroup
 }
 
 // PaymentURLRequest is the information that you need to provide when creating a Payment URL
 // Note that all fields are not mandatory. Take a look at Instamojo Documentation for more
 // Information at https://docs.instamojo.com
+// ExpiresAt is only sent when it is set, Use an ExpiryScheduler on API versions that do not support it
 type PaymentURLRequest struct {
-	Purpose               string `json:"purpose"`
-	Amount                int    `json:"amount"`
-	Phone                 string `json:"phone"`
-	BuyerName             string `json:"buyer_name"`
-	RedirectURL           string `json:"redirect_url"`
-	SendEmail             bool   `json:"send_email"`
-	Webhook               string `json:"webhook"`
-	SendSms               bool   `json:"send_sms"`
-	Email                 string `json:"email"`
-	AllowRepeatedPayments bool   `json:"allow_repeated_payments"`
+	Purpose               string     `json:"purpose"`
+	Amount                int        `json:"amount"`
+	Phone                 string     `json:"phone"`
+	BuyerName             string     `json:"buyer_name"`
+	RedirectURL           string     `json:"redirect_url"`
+	SendEmail             bool       `json:"send_email"`
+	Webhook               string     `json:"webhook"`
+	SendSms               bool       `json:"send_sms"`
+	Email                 string     `json:"email"`
+	AllowRepeatedPayments bool       `json:"allow_repeated_payments"`
+	ExpiresAt             *time.Time `json:"expires_at,omitempty"`
 }
 
 // PaymentURLResponse is returned when creating a new payment URL
 type PaymentURLResponse struct {
 	PaymentRequest struct {
-		ID                    string    `json:"id"`
-		Phone                 string    `json:"phone"`
-		Email                 string    `json:"email"`
-		BuyerName             string    `json:"buyer_name"`
-		Amount                string    `json:"amount"`
-		Purpose               string    `json:"purpose"`
-		Status                string    `json:"status"`
-		SendSms               bool      `json:"send_sms"`
-		SendEmail             bool      `json:"send_email"`
-		SmsStatus             string    `json:"sms_status"`
-		EmailStatus           string    `json:"email_status"`
-		Shorturl              string    `json:"shorturl"`
-		Longurl               string    `json:"longurl"`
-		RedirectURL           string    `json:"redirect_url"`
-		Webhook               string    `json:"webhook"`
-		CreatedAt             time.Time `json:"created_at"`
-		ModifiedAt            time.Time `json:"modified_at"`
-		AllowRepeatedPayments bool      `json:"allow_repeated_payments"`
+		ID                    string     `json:"id"`
+		Phone                 string     `json:"phone"`
+		Email                 string     `json:"email"`
+		BuyerName             string     `json:"buyer_name"`
+		Amount                string     `json:"amount"`
+		Purpose               string     `json:"purpose"`
+		Status                string     `json:"status"`
+		SendSms               bool       `json:"send_sms"`
+		SendEmail             bool       `json:"send_email"`
+		SmsStatus             string     `json:"sms_status"`
+		EmailStatus           string     `json:"email_status"`
+		Shorturl              string     `json:"shorturl"`
+		Longurl               string     `json:"longurl"`
+		RedirectURL           string     `json:"redirect_url"`
+		Webhook               string     `json:"webhook"`
+		CreatedAt             time.Time  `json:"created_at"`
+		ModifiedAt            time.Time  `json:"modified_at"`
+		ExpiresAt             *time.Time `json:"expires_at"`
+		AllowRepeatedPayments bool       `json:"allow_repeated_payments"`
 	} `json:"payment_request"`
 	Success bool `json:"success"`
 }
@@ -93,7 +151,7 @@ type WebhookResponse struct {
 	Mac              string `json:"mac"`
 }
 
-//RequestsList is the list of all the requests created so far
+// RequestsList is the list of all the requests created so far
 type RequestsList struct {
 	Success         bool `json:"success"`
 	PaymentRequests []struct {
@@ -118,6 +176,43 @@ type RequestsList struct {
 	} `json:"payment_requests"`
 }
 
+// ListRequestsOptions filters the payment requests returned by ListRequestsWithOptions.
+// Zero values are left out of the request, Page starts at 1
+type ListRequestsOptions struct {
+	MinCreatedAt  time.Time
+	MaxCreatedAt  time.Time
+	MinModifiedAt time.Time
+	MaxModifiedAt time.Time
+	Page          int
+	Limit         int
+}
+
+func (o *ListRequestsOptions) query() url.Values {
+	q := url.Values{}
+	if o == nil {
+		return q
+	}
+
+	times := map[string]time.Time{
+		"min_created_at":  o.MinCreatedAt,
+		"max_created_at":  o.MaxCreatedAt,
+		"min_modified_at": o.MinModifiedAt,
+		"max_modified_at": o.MaxModifiedAt,
+	}
+	for k, t := range times {
+		if !t.IsZero() {
+			q.Set(k, t.UTC().Format(time.RFC3339))
+		}
+	}
+	if o.Page > 0 {
+		q.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return q
+}
+
 // PaymentRequestDetails is the response that has complete details about a Payment ID
 type PaymentRequestDetails struct {
 	PaymentRequest struct {
@@ -164,9 +259,10 @@ type PaymentRequestDetails struct {
 			CreatedAt           time.Time   `json:"created_at"`
 			PaymentRequest      string      `json:"payment_request"`
 		} `json:"payments"`
-		CreatedAt             time.Time `json:"created_at"`
-		ModifiedAt            time.Time `json:"modified_at"`
-		AllowRepeatedPayments bool      `json:"allow_repeated_payments"`
+		CreatedAt             time.Time  `json:"created_at"`
+		ModifiedAt            time.Time  `json:"modified_at"`
+		ExpiresAt             *time.Time `json:"expires_at"`
+		AllowRepeatedPayments bool       `json:"allow_repeated_payments"`
 	} `json:"payment_request"`
 	Success bool `json:"success"`
 }
@@ -259,6 +355,7 @@ type PaymentDetails struct {
 	Success bool `json:"success"`
 }
 
-type successResponse struct {
+// SuccessResponse is returned by the endpoints that only report whether they succeeded
+type SuccessResponse struct {
 	Success bool `json:"success"`
 }
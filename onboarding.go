@@ -0,0 +1,510 @@
+package instamojo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token is an OAuth2 token of the v2 API.
+// Expiry is worked out from ExpiresIn when the token is received, So a Token that was saved as JSON
+// still knows when it expires after it is loaded again
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type"`
+	ExpiresIn    int       `json:"expires_in"`
+	Scope        string    `json:"scope"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// tokenExpiryDelta is how long before its Expiry a Token is treated as expired,
+// So it does not expire while a request is on its way
+const tokenExpiryDelta = 30 * time.Second
+
+// valid reports whether t can still be used
+func (t *Token) valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(tokenExpiryDelta).Before(t.Expiry))
+}
+
+// SignupRequest signs up a new user under the application. Referrer is the username of the
+// partner that refers the user, It is sent only when it is set
+type SignupRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Phone    string `json:"phone"`
+	Referrer string `json:"referrer,omitempty"`
+}
+
+func (r *SignupRequest) values() url.Values {
+	v := url.Values{}
+	v.Set("email", r.Email)
+	v.Set("password", r.Password)
+	v.Set("phone", r.Phone)
+	if r.Referrer != "" {
+		v.Set("referrer", r.Referrer)
+	}
+	return v
+}
+
+func (r *SignupRequest) validate() error {
+	e := &ValidationError{}
+	if r.Email == "" {
+		e.add("email", "This field is required.")
+	}
+	if r.Password == "" {
+		e.add("password", "This field is required.")
+	}
+	if r.Phone == "" {
+		e.add("phone", "This field is required.")
+	}
+	return e.orNil()
+}
+
+// User is a user of the v2 API
+type User struct {
+	ID          string    `json:"id"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	Phone       string    `json:"phone"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	Location    string    `json:"location"`
+	DateJoined  time.Time `json:"date_joined"`
+	ResourceURI string    `json:"resource_uri"`
+}
+
+// UserDetails are the KYC details of a user. Only the fields that are set are updated
+type UserDetails struct {
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+	Location  string `json:"location,omitempty"`
+	Phone     string `json:"phone,omitempty"`
+}
+
+func (d *UserDetails) values() url.Values {
+	v := url.Values{}
+	for k, s := range map[string]string{"first_name": d.FirstName, "last_name": d.LastName, "location": d.Location, "phone": d.Phone} {
+		if s != "" {
+			v.Set(k, s)
+		}
+	}
+	return v
+}
+
+// BankAccount is the Indian bank account that the payments of a user are settled to.
+// BankName is filled in by instamojo and is ignored in updates
+type BankAccount struct {
+	AccountHolderName string `json:"account_holder_name"`
+	AccountNumber     string `json:"account_number"`
+	IFSCCode          string `json:"ifsc_code"`
+	BankName          string `json:"bank_name,omitempty"`
+}
+
+func (b *BankAccount) values() url.Values {
+	v := url.Values{}
+	v.Set("account_holder_name", b.AccountHolderName)
+	v.Set("account_number", b.AccountNumber)
+	v.Set("ifsc_code", b.IFSCCode)
+	return v
+}
+
+func (b *BankAccount) validate() error {
+	e := &ValidationError{}
+	if b.AccountHolderName == "" {
+		e.add("account_holder_name", "This field is required.")
+	}
+	if b.AccountNumber == "" {
+		e.add("account_number", "This field is required.")
+	}
+	if len(b.IFSCCode) != 11 {
+		e.add("ifsc_code", "IFSC code must be 11 characters long.")
+	}
+	return e.orNil()
+}
+
+// ValidationError is returned when a request of the v2 API is rejected because of its fields,
+// Either by instamojo or before it is sent. Fields maps the name of every invalid field to what is wrong with it
+type ValidationError struct {
+	Fields map[string][]string
+}
+
+func (e *ValidationError) Error() string {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	problems := make([]string, len(keys))
+	for i, k := range keys {
+		problems[i] = k + ": " + strings.Join(e.Fields[k], " ")
+	}
+	return "instamojo: invalid request: " + strings.Join(problems, "; ")
+}
+
+func (e *ValidationError) add(field, problem string) {
+	if e.Fields == nil {
+		e.Fields = make(map[string][]string)
+	}
+	e.Fields[field] = append(e.Fields[field], problem)
+}
+
+func (e *ValidationError) orNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// validationError reads the errors of every field from a 400 response of the v2 API
+func validationError(resp *http.Response) error {
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("unrecognized response from instamojo: %s", string(b))
+	}
+
+	e := &ValidationError{}
+	var add func(field string, v interface{})
+	add = func(field string, v interface{}) {
+		switch v := v.(type) {
+		case string:
+			e.add(field, v)
+		case []interface{}:
+			for _, p := range v {
+				add(field, p)
+			}
+		case map[string]interface{}:
+			for k, p := range v {
+				add(k, p)
+			}
+		}
+	}
+	for k, v := range m {
+		if k != "success" {
+			add(k, v)
+		}
+	}
+
+	if len(e.Fields) == 0 {
+		return fmt.Errorf("unrecognized response from instamojo: %s", string(b))
+	}
+	return e
+}
+
+// OAuthError is returned when instamojo refuses to issue a Token, Code is the OAuth2 error code like "invalid_grant"
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return "instamojo: oauth error " + e.Code
+	}
+	return "instamojo: oauth error " + e.Code + ": " + e.Description
+}
+
+// Onboarding signs up and manages the users of a partner application with instamojo's v2 API.
+// It authenticates with the client id and secret of the application instead of an API key and auth token,
+// And keeps an application token that it renews when it expires.
+// Every user that logs in gets a UserSession with a token of its own
+type Onboarding struct {
+	config       Config
+	clientID     string
+	clientSecret string
+
+	mu  sync.Mutex
+	app *Token
+}
+
+// NewOnboarding creates an Onboarding for the application with clientID and clientSecret.
+// Options that configure the transport, Like WithSandbox, WithBaseURL, WithHTTPClient, WithRetries,
+// WithLogger, WithMetrics and WithMiddleware, Apply to it the same way they do to a Client
+func NewOnboarding(clientID, clientSecret string, opts ...Option) (*Onboarding, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("invalid client credentials")
+	}
+
+	c := Config{}
+	for _, o := range opts {
+		o(&c)
+	}
+	if c.Retries < 0 {
+		return nil, fmt.Errorf("invalid retries: %d", c.Retries)
+	}
+	if err := c.setEndpoint(); err != nil {
+		return nil, err
+	}
+
+	return &Onboarding{config: c, clientID: clientID, clientSecret: clientSecret}, nil
+}
+
+// url returns the absolute URL of an endpoint of the v2 API
+func (o *Onboarding) url(format string, a ...interface{}) string {
+	endpoint := o.config.endpoint
+	if o.config.BaseURL == "" && !o.config.SandboxMode {
+		endpoint = ProductionAPIURL
+	}
+	return endpoint + "/" + fmt.Sprintf(format, a...)
+}
+
+// makeRequest sends form to the v2 API with token as the bearer token, Retrying it like Config.makeRequest does
+func (o *Onboarding) makeRequest(op Operation, m, url string, form url.Values, token string) (*http.Response, error) {
+	auth := requestAuth{
+		set: func(req *http.Request) error {
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			return nil
+		},
+	}
+
+	return o.config.sendRequest(context.Background(), op, m, url, []byte(form.Encode()), "application/x-www-form-urlencoded", auth)
+}
+
+// decodeResponse decodes resp into v when it has the status that the endpoint answers with on success
+func decodeResponse(resp *http.Response, status int, v interface{}) error {
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case status:
+		return json.NewDecoder(resp.Body).Decode(v)
+	case 400:
+		return validationError(resp)
+	case 401:
+		return unauthorized(resp)
+	}
+
+	return defaultResponse(resp)
+}
+
+// token asks for a Token with the grant in form
+func (o *Onboarding) token(op Operation, form url.Values) (*Token, error) {
+	form.Set("client_id", o.clientID)
+	form.Set("client_secret", o.clientSecret)
+
+	resp, err := o.makeRequest(op, "POST", o.url("oauth2/token/"), form, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+		t := &Token{}
+		if err := json.NewDecoder(resp.Body).Decode(t); err != nil {
+			return nil, err
+		}
+		if t.ExpiresIn > 0 {
+			t.Expiry = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+		}
+		return t, nil
+	case 400, 401:
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		oe := &OAuthError{}
+		if json.Unmarshal(b, oe) != nil || oe.Code == "" {
+			return nil, fmt.Errorf("unrecognized response from instamojo: %s", string(b))
+		}
+		return nil, oe
+	}
+
+	return nil, defaultResponse(resp)
+}
+
+// appToken returns the application token, Getting a new one if there is none or it has expired
+func (o *Onboarding) appToken(renew bool) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !renew && o.app.valid() {
+		return o.app.AccessToken, nil
+	}
+
+	t, err := o.token(Operation{Name: "ApplicationToken"}, url.Values{"grant_type": {"client_credentials"}})
+	if err != nil {
+		return "", err
+	}
+	o.app = t
+	return t.AccessToken, nil
+}
+
+// SignUp creates a user under the application. It returns a *ValidationError without
+// making a request if the email, password or phone is missing
+func (o *Onboarding) SignUp(r *SignupRequest) (*User, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	for renewed := false; ; renewed = true {
+		token, err := o.appToken(renewed)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := o.makeRequest(Operation{Name: "SignUp"}, "POST", o.url("v2/users/"), r.values(), token)
+		if err != nil {
+			return nil, err
+		}
+
+		// The application token may have been revoked before it expired, Get a new one and try once more
+		if resp.StatusCode == 401 && !renewed {
+			resp.Body.Close()
+			continue
+		}
+
+		u := &User{}
+		if err := decodeResponse(resp, 201, u); err != nil {
+			return nil, err
+		}
+		return u, nil
+	}
+}
+
+// Login gets a token for a user with their username and password
+func (o *Onboarding) Login(username, password string) (*UserSession, error) {
+	t, err := o.token(Operation{Name: "Login"}, url.Values{
+		"grant_type": {"password"},
+		"username":   {username},
+		"password":   {password},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UserSession{onboarding: o, token: t}, nil
+}
+
+// Session resumes the session of a user from a Token that was saved earlier
+func (o *Onboarding) Session(t *Token) *UserSession {
+	tc := *t
+	return &UserSession{onboarding: o, token: &tc}
+}
+
+// UserSession makes requests on behalf of one user with their token.
+// The token is refreshed with its refresh token when it expires, Or when instamojo rejects it.
+// OnToken, If it is set, Is called with every new token so that it can be saved. Set it before the session is shared
+type UserSession struct {
+	OnToken func(*Token)
+
+	onboarding *Onboarding
+	mu         sync.Mutex
+	token      *Token
+}
+
+// Token returns the current token of the user, Refreshing it first if it has expired
+func (s *UserSession) Token() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.token.valid() {
+		if err := s.refresh(); err != nil {
+			return nil, err
+		}
+	}
+	t := *s.token
+	return &t, nil
+}
+
+// Refresh gets a new token for the user with the refresh token
+func (s *UserSession) Refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refresh()
+}
+
+func (s *UserSession) refresh() error {
+	if s.token.RefreshToken == "" {
+		return fmt.Errorf("instamojo: token has no refresh token")
+	}
+
+	t, err := s.onboarding.token(Operation{Name: "RefreshToken"}, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {s.token.RefreshToken},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The refresh token is kept if instamojo does not rotate it
+	if t.RefreshToken == "" {
+		t.RefreshToken = s.token.RefreshToken
+	}
+	s.token = t
+
+	if s.OnToken != nil {
+		tc := *t
+		s.OnToken(&tc)
+	}
+	return nil
+}
+
+// call sends a request with the token of the user and decodes the response into v,
+// Refreshing the token once if instamojo rejects it
+func (s *UserSession) call(op Operation, m, url string, form url.Values, status int, v interface{}) error {
+	for refreshed := false; ; refreshed = true {
+		t, err := s.Token()
+		if err != nil {
+			return err
+		}
+
+		resp, err := s.onboarding.makeRequest(op, m, url, form, t.AccessToken)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == 401 && !refreshed {
+			resp.Body.Close()
+			if err := s.Refresh(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		return decodeResponse(resp, status, v)
+	}
+}
+
+// UpdateDetails updates the KYC details of the user with userID
+func (s *UserSession) UpdateDetails(userID string, d *UserDetails) (*User, error) {
+	form := d.values()
+	if len(form) == 0 {
+		return nil, &ValidationError{Fields: map[string][]string{"details": {"No details to update."}}}
+	}
+
+	u := &User{}
+	err := s.call(Operation{Name: "UpdateDetails", ID: userID}, "PATCH", s.onboarding.url("v2/users/%s/", url.PathEscape(userID)), form, 200, u)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// UpdateBankAccount sets the bank account that the payments of the user with userID are settled to.
+// It returns a *ValidationError without making a request if a field is missing or the IFSC code is malformed
+func (s *UserSession) UpdateBankAccount(userID string, b *BankAccount) (*BankAccount, error) {
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+
+	ba := &BankAccount{}
+	err := s.call(Operation{Name: "UpdateBankAccount", ID: userID}, "PUT", s.onboarding.url("v2/users/%s/inrbankaccount/", url.PathEscape(userID)), b.values(), 200, ba)
+	if err != nil {
+		return nil, err
+	}
+	return ba, nil
+}
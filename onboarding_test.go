@@ -0,0 +1,163 @@
+package instamojo_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestOnboarding(t *testing.T) {
+
+	var appTokens, refreshes int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/oauth2/token/":
+			if r.PostForm.Get("client_id") != "id" || r.PostForm.Get("client_secret") != "secret" {
+				w.WriteHeader(401)
+				fmt.Fprint(w, `{"error": "invalid_client"}`)
+				return
+			}
+
+			switch r.PostForm.Get("grant_type") {
+			case "client_credentials":
+				atomic.AddInt32(&appTokens, 1)
+				fmt.Fprint(w, `{"access_token": "app", "token_type": "Bearer", "expires_in": 36000, "scope": "read write"}`)
+			case "password":
+				if r.PostForm.Get("password") != "hunter2" {
+					w.WriteHeader(400)
+					fmt.Fprint(w, `{"error": "invalid_grant", "error_description": "Invalid credentials given."}`)
+					return
+				}
+				fmt.Fprint(w, `{"access_token": "stale", "refresh_token": "refresh", "token_type": "Bearer", "expires_in": 36000}`)
+			case "refresh_token":
+				atomic.AddInt32(&refreshes, 1)
+				fmt.Fprint(w, `{"access_token": "fresh", "token_type": "Bearer", "expires_in": 36000}`)
+			}
+
+		case "/v2/users/":
+			if r.Header.Get("Authorization") != "Bearer app" {
+				w.WriteHeader(401)
+				return
+			}
+			if r.PostForm.Get("email") == "taken@example.com" {
+				w.WriteHeader(400)
+				fmt.Fprint(w, `{"success": false, "email": ["A user with that email already exists."]}`)
+				return
+			}
+			w.WriteHeader(201)
+			fmt.Fprintf(w, `{"id": "u1", "username": "seller", "email": %q, "phone": %q}`, r.PostForm.Get("email"), r.PostForm.Get("phone"))
+
+		case "/v2/users/u1/", "/v2/users/u1/inrbankaccount/":
+			// The token from the password grant has been revoked
+			if r.Header.Get("Authorization") != "Bearer fresh" {
+				w.WriteHeader(401)
+				fmt.Fprint(w, `{"success": false, "message": "Invalid token."}`)
+				return
+			}
+			if r.Method == "PATCH" {
+				fmt.Fprintf(w, `{"id": "u1", "first_name": %q, "location": %q}`, r.PostForm.Get("first_name"), r.PostForm.Get("location"))
+				return
+			}
+			fmt.Fprintf(w, `{"account_holder_name": %q, "account_number": %q, "ifsc_code": %q, "bank_name": "HDFC"}`,
+				r.PostForm.Get("account_holder_name"), r.PostForm.Get("account_number"), r.PostForm.Get("ifsc_code"))
+
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	if _, err := instamojo.NewOnboarding("", "secret"); err == nil {
+		t.Error("onboarding created without a client id")
+	}
+
+	o, err := instamojo.NewOnboarding("id", "secret", instamojo.WithBaseURL(ts.URL), instamojo.WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = o.SignUp(&instamojo.SignupRequest{Email: "seller@example.com"})
+	var ve *instamojo.ValidationError
+	if !errors.As(err, &ve) || len(ve.Fields["password"]) != 1 || len(ve.Fields["phone"]) != 1 {
+		t.Errorf("Got error %v for an incomplete signup", err)
+	}
+
+	u, err := o.SignUp(&instamojo.SignupRequest{Email: "seller@example.com", Password: "hunter2", Phone: "9999999999"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != "u1" || u.Email != "seller@example.com" {
+		t.Errorf("Got user %+v", u)
+	}
+
+	_, err = o.SignUp(&instamojo.SignupRequest{Email: "taken@example.com", Password: "hunter2", Phone: "9999999999"})
+	if !errors.As(err, &ve) || ve.Fields["email"][0] != "A user with that email already exists." {
+		t.Errorf("Got error %v for a taken email", err)
+	}
+	if n := atomic.LoadInt32(&appTokens); n != 1 {
+		t.Errorf("Got %d application tokens", n)
+	}
+
+	_, err = o.Login("seller", "wrong")
+	var oe *instamojo.OAuthError
+	if !errors.As(err, &oe) || oe.Code != "invalid_grant" {
+		t.Errorf("Got error %v for a wrong password", err)
+	}
+
+	s, err := o.Login("seller", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved *instamojo.Token
+	s.OnToken = func(t *instamojo.Token) { saved = t }
+
+	u, err = s.UpdateDetails("u1", &instamojo.UserDetails{FirstName: "Asha", Location: "Karnataka"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.FirstName != "Asha" || u.Location != "Karnataka" {
+		t.Errorf("Got user %+v", u)
+	}
+	if saved == nil || saved.AccessToken != "fresh" || saved.RefreshToken != "refresh" {
+		t.Errorf("Saved token %#v", saved)
+	}
+
+	if _, err := s.UpdateBankAccount("u1", &instamojo.BankAccount{AccountHolderName: "Asha", AccountNumber: "1234567890", IFSCCode: "HDFC0"}); !errors.As(err, &ve) {
+		t.Errorf("Got error %v for a malformed IFSC code", err)
+	}
+	ba, err := s.UpdateBankAccount("u1", &instamojo.BankAccount{AccountHolderName: "Asha", AccountNumber: "1234567890", IFSCCode: "HDFC0000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ba.BankName != "HDFC" || ba.AccountNumber != "1234567890" {
+		t.Errorf("Got bank account %+v", ba)
+	}
+	if n := atomic.LoadInt32(&refreshes); n != 1 {
+		t.Errorf("Refreshed the token %d times", n)
+	}
+	if out := fmt.Sprintf("%v", *ba); strings.Contains(out, "1234567890") {
+		t.Errorf("Account number was not redacted in %s", out)
+	}
+
+	// A saved token that has expired is refreshed before it is used
+	expired := o.Session(&instamojo.Token{AccessToken: "old", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Minute)})
+	tok, err := expired.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "fresh" {
+		t.Errorf("Got token %v", tok.AccessToken)
+	}
+}
@@ -0,0 +1,145 @@
+// Package otelinstamojo instruments the instamojo package with OpenTelemetry.
+// It is a separate module, So programs that do not use OpenTelemetry do not depend on it.
+//
+// Spans are started from the context of the request. Only Config.Do takes a context,
+// The other methods of Config send their requests with context.Background(), So their spans
+// are root spans that do not join the trace of the caller
+package otelinstamojo
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ishanjain28/instamojo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/ishanjain28/instamojo/otelinstamojo"
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// Option configures Middleware
+type Option func(*config)
+
+// WithTracerProvider sets the TracerProvider that spans are created with, The global one is used by default
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider sets the MeterProvider that metrics are recorded with, The global one is used by default
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = mp
+	}
+}
+
+// Middleware returns an instamojo.Middleware that records a client span for every request,
+// Following the HTTP client semantic conventions, With the id of the payment request,
+// payment or refund as the instamojo.id attribute.
+//
+// It also records these metrics, By operation, method and status code:
+//
+//	instamojo.client.requests          number of requests
+//	instamojo.client.request.duration  latency of the requests, in seconds
+//	instamojo.client.errors            requests that failed or got a 4xx or 5xx response
+func Middleware(opts ...Option) instamojo.Middleware {
+	c := &config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+
+	tracer := c.tracerProvider.Tracer(instrumentationName)
+	meter := c.meterProvider.Meter(instrumentationName)
+
+	requests, err := meter.Int64Counter("instamojo.client.requests",
+		metric.WithDescription("Number of requests sent to instamojo"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	duration, err := meter.Float64Histogram("instamojo.client.request.duration",
+		metric.WithDescription("Latency of the requests sent to instamojo"),
+		metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	failures, err := meter.Int64Counter("instamojo.client.errors",
+		metric.WithDescription("Number of requests to instamojo that failed"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return func(next instamojo.RoundTripFunc) instamojo.RoundTripFunc {
+		return func(op instamojo.Operation, req *http.Request) (*http.Response, error) {
+			attrs := []attribute.KeyValue{
+				attribute.String("http.request.method", req.Method),
+				attribute.String("server.address", req.URL.Hostname()),
+				attribute.String("url.full", req.URL.String()),
+				attribute.String("instamojo.operation", op.Name),
+			}
+			if port := req.URL.Port(); port != "" {
+				if p, err := strconv.Atoi(port); err == nil {
+					attrs = append(attrs, attribute.Int("server.port", p))
+				}
+			}
+			if op.ID != "" {
+				attrs = append(attrs, attribute.String("instamojo.id", op.ID))
+			}
+
+			ctx, span := tracer.Start(req.Context(), "instamojo."+op.Name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attrs...))
+			defer span.End()
+
+			start := time.Now()
+			resp, err := next(op, req.WithContext(ctx))
+			elapsed := time.Since(start).Seconds()
+
+			metricAttrs := []attribute.KeyValue{
+				attribute.String("instamojo.operation", op.Name),
+				attribute.String("http.request.method", req.Method),
+			}
+
+			failed := false
+			switch {
+			case err != nil:
+				failed = true
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				span.SetAttributes(attribute.String("error.type", "request"))
+				metricAttrs = append(metricAttrs, attribute.String("error.type", "request"))
+			default:
+				status := attribute.Int("http.response.status_code", resp.StatusCode)
+				span.SetAttributes(status)
+				metricAttrs = append(metricAttrs, status)
+
+				if resp.StatusCode >= 400 {
+					failed = true
+					span.SetStatus(codes.Error, resp.Status)
+					span.SetAttributes(attribute.String("error.type", strconv.Itoa(resp.StatusCode)))
+				}
+			}
+
+			set := metric.WithAttributes(metricAttrs...)
+			requests.Add(ctx, 1, set)
+			duration.Record(ctx, elapsed, set)
+			if failed {
+				failures.Add(ctx, 1, set)
+			}
+
+			return resp, err
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package otelinstamojo_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+	"github.com/ishanjain28/instamojo/otelinstamojo"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddleware(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/1.1/refunds/C1" {
+			w.WriteHeader(401)
+			fmt.Fprint(w, `{"success": false, "message": "invalid token"}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO1", "status": "Credit"}}`)
+	}))
+	defer ts.Close()
+
+	spans := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	c, err := instamojo.Init(&instamojo.Config{
+		APIKey:    "key",
+		AuthToken: "token",
+		BaseURL:   ts.URL,
+		Middleware: []instamojo.Middleware{
+			otelinstamojo.Middleware(otelinstamojo.WithTracerProvider(tp), otelinstamojo.WithMeterProvider(mp)),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.PaymentDetails("MOJO1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RefundDetails("C1"); err == nil {
+		t.Fatal("unauthorized refund lookup succeeded")
+	}
+
+	got := spans.GetSpans()
+	if len(got) != 2 {
+		t.Fatalf("Got %d spans, want 2", len(got))
+	}
+	if got[0].Name != "instamojo.PaymentDetails" {
+		t.Errorf("Got span %q, want instamojo.PaymentDetails", got[0].Name)
+	}
+	if !hasAttribute(got[0].Attributes, attribute.String("instamojo.id", "MOJO1")) {
+		t.Errorf("span has no instamojo.id: %v", got[0].Attributes)
+	}
+	if !hasAttribute(got[1].Attributes, attribute.Int("http.response.status_code", 401)) {
+		t.Errorf("span has no status code: %v", got[1].Attributes)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+
+	totals := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, dp := range sum.DataPoints {
+					totals[m.Name] += dp.Value
+				}
+			}
+		}
+	}
+	if totals["instamojo.client.requests"] != 2 || totals["instamojo.client.errors"] != 1 {
+		t.Errorf("Got %v, want 2 requests and 1 error", totals)
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
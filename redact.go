@@ -0,0 +1,261 @@
+package instamojo
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// redacted replaces secrets in logs and formatted output
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders are the headers whose values are never logged
+var sensitiveHeaders = []string{"X-Api-Key", "X-Auth-Token", "Authorization", "Cookie", "Set-Cookie"}
+
+// redactHeaders returns a copy of h with the values of the sensitive headers replaced
+func redactHeaders(h http.Header) http.Header {
+	r := h.Clone()
+	for _, k := range sensitiveHeaders {
+		if r.Get(k) != "" {
+			r.Set(k, redacted)
+		}
+	}
+	return r
+}
+
+// redactEmail keeps the first letter of the mailbox and the domain, abc@xyz.com becomes a**@xyz.com
+func redactEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return redacted
+	}
+	return email[:1] + strings.Repeat("*", at-1) + email[at:]
+}
+
+// redactPhone keeps the last four digits of a phone number
+func redactPhone(phone string) string {
+	if len(phone) <= 4 {
+		return strings.Repeat("*", len(phone))
+	}
+	return strings.Repeat("*", len(phone)-4) + phone[len(phone)-4:]
+}
+
+// The models below print with the buyer's email and phone redacted, With any fmt verb.
+// They also implement slog.LogValuer, So handlers that do not use fmt redact them too.
+// encoding/json is not affected
+
+type webhookResponse WebhookResponse
+
+func (w WebhookResponse) redact() webhookResponse {
+	r := webhookResponse(w)
+	r.Buyer = redactEmail(r.Buyer)
+	r.BuyerPhone = redactPhone(r.BuyerPhone)
+	return r
+}
+
+// Format formats the WebhookResponse with the buyer's email and phone redacted
+func (w WebhookResponse) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), w.redact())
+}
+
+// LogValue logs the WebhookResponse with the buyer's email and phone redacted
+func (w WebhookResponse) LogValue() slog.Value {
+	return slog.AnyValue(w.redact())
+}
+
+type paymentURLRequest PaymentURLRequest
+
+func (p PaymentURLRequest) redact() paymentURLRequest {
+	r := paymentURLRequest(p)
+	r.Email = redactEmail(r.Email)
+	r.Phone = redactPhone(r.Phone)
+	return r
+}
+
+// Format formats the PaymentURLRequest with the buyer's email and phone redacted
+func (p PaymentURLRequest) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), p.redact())
+}
+
+// LogValue logs the PaymentURLRequest with the buyer's email and phone redacted
+func (p PaymentURLRequest) LogValue() slog.Value {
+	return slog.AnyValue(p.redact())
+}
+
+type paymentURLResponse PaymentURLResponse
+
+func (p PaymentURLResponse) redact() paymentURLResponse {
+	r := paymentURLResponse(p)
+	r.PaymentRequest.Email = redactEmail(r.PaymentRequest.Email)
+	r.PaymentRequest.Phone = redactPhone(r.PaymentRequest.Phone)
+	return r
+}
+
+// Format formats the PaymentURLResponse with the buyer's email and phone redacted
+func (p PaymentURLResponse) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), p.redact())
+}
+
+// LogValue logs the PaymentURLResponse with the buyer's email and phone redacted
+func (p PaymentURLResponse) LogValue() slog.Value {
+	return slog.AnyValue(p.redact())
+}
+
+type paymentDetails PaymentDetails
+
+func (p PaymentDetails) redact() paymentDetails {
+	r := paymentDetails(p)
+	r.Payment.BuyerEmail = redactEmail(r.Payment.BuyerEmail)
+	r.Payment.BuyerPhone = redactPhone(r.Payment.BuyerPhone)
+	return r
+}
+
+// Format formats the PaymentDetails with the buyer's email and phone redacted
+func (p PaymentDetails) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), p.redact())
+}
+
+// LogValue logs the PaymentDetails with the buyer's email and phone redacted
+func (p PaymentDetails) LogValue() slog.Value {
+	return slog.AnyValue(p.redact())
+}
+
+type paymentRequestDetails PaymentRequestDetails
+
+func (p PaymentRequestDetails) redact() paymentRequestDetails {
+	r := paymentRequestDetails(p)
+	r.PaymentRequest.Email = redactEmail(r.PaymentRequest.Email)
+	r.PaymentRequest.Phone = redactPhone(r.PaymentRequest.Phone)
+
+	// Payments is shared with p, So it is copied before it is changed
+	r.PaymentRequest.Payments = append(r.PaymentRequest.Payments[:0:0], r.PaymentRequest.Payments...)
+	for i := range r.PaymentRequest.Payments {
+		r.PaymentRequest.Payments[i].BuyerEmail = redactEmail(r.PaymentRequest.Payments[i].BuyerEmail)
+		r.PaymentRequest.Payments[i].BuyerPhone = redactPhone(r.PaymentRequest.Payments[i].BuyerPhone)
+	}
+	return r
+}
+
+// Format formats the PaymentRequestDetails with the buyer's email and phone redacted
+func (p PaymentRequestDetails) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), p.redact())
+}
+
+// LogValue logs the PaymentRequestDetails with the buyer's email and phone redacted
+func (p PaymentRequestDetails) LogValue() slog.Value {
+	return slog.AnyValue(p.redact())
+}
+
+type requestsList RequestsList
+
+func (l RequestsList) redact() requestsList {
+	r := requestsList(l)
+
+	// PaymentRequests is shared with l, So it is copied before it is changed
+	r.PaymentRequests = append(r.PaymentRequests[:0:0], r.PaymentRequests...)
+	for i := range r.PaymentRequests {
+		r.PaymentRequests[i].Email = redactEmail(r.PaymentRequests[i].Email)
+		r.PaymentRequests[i].Phone = redactPhone(r.PaymentRequests[i].Phone)
+	}
+	return r
+}
+
+// Format formats the RequestsList with the buyers' emails and phones redacted
+func (l RequestsList) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), l.redact())
+}
+
+// LogValue logs the RequestsList with the buyers' emails and phones redacted
+func (l RequestsList) LogValue() slog.Value {
+	return slog.AnyValue(l.redact())
+}
+
+// The onboarding models below print with their secrets redacted in the same way
+
+type user User
+
+func (u User) redact() user {
+	r := user(u)
+	r.Email = redactEmail(r.Email)
+	r.Phone = redactPhone(r.Phone)
+	return r
+}
+
+// Format formats the User with the email and phone redacted
+func (u User) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), u.redact())
+}
+
+// LogValue logs the User with the email and phone redacted
+func (u User) LogValue() slog.Value {
+	return slog.AnyValue(u.redact())
+}
+
+type token Token
+
+func (t Token) redact() token {
+	r := token(t)
+	if r.AccessToken != "" {
+		r.AccessToken = redacted
+	}
+	if r.RefreshToken != "" {
+		r.RefreshToken = redacted
+	}
+	return r
+}
+
+// Format formats the Token with the access and refresh tokens redacted
+func (t Token) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), t.redact())
+}
+
+// LogValue logs the Token with the access and refresh tokens redacted
+func (t Token) LogValue() slog.Value {
+	return slog.AnyValue(t.redact())
+}
+
+type signupRequest SignupRequest
+
+func (s SignupRequest) redact() signupRequest {
+	r := signupRequest(s)
+	r.Email = redactEmail(r.Email)
+	r.Phone = redactPhone(r.Phone)
+	if r.Password != "" {
+		r.Password = redacted
+	}
+	return r
+}
+
+// Format formats the SignupRequest with the email and phone redacted and the password removed
+func (s SignupRequest) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), s.redact())
+}
+
+// LogValue logs the SignupRequest with the email and phone redacted and the password removed
+func (s SignupRequest) LogValue() slog.Value {
+	return slog.AnyValue(s.redact())
+}
+
+type bankAccount BankAccount
+
+func (b BankAccount) redact() bankAccount {
+	r := bankAccount(b)
+	// Account numbers are redacted like phone numbers, Keeping the last four digits
+	r.AccountNumber = redactPhone(r.AccountNumber)
+	return r
+}
+
+// Format formats the BankAccount with all but the last four digits of the account number redacted
+func (b BankAccount) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), b.redact())
+}
+
+// LogValue logs the BankAccount with all but the last four digits of the account number redacted
+func (b BankAccount) LogValue() slog.Value {
+	return slog.AnyValue(b.redact())
+}
@@ -0,0 +1,106 @@
+package instamojo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Errors returned when a redirect can not be confirmed with Instamojo
+var (
+	ErrMissingPaymentID        = errors.New("instamojo: redirect has no payment_id")
+	ErrMissingPaymentRequestID = errors.New("instamojo: redirect has no payment_request_id")
+	ErrPaymentNotCredited      = errors.New("instamojo: payment is not credited")
+	ErrPaymentRequestMismatch  = errors.New("instamojo: payment does not belong to the payment request")
+)
+
+// RedirectResponse is the data that Instamojo appends to the RedirectURL
+// when it sends the buyer back after a payment.
+// The buyer can edit these values, Use ConfirmRedirect before trusting them
+type RedirectResponse struct {
+	PaymentID        string `json:"payment_id"`
+	PaymentStatus    string `json:"payment_status"`
+	PaymentRequestID string `json:"payment_request_id"`
+}
+
+// ParseRedirectResponse parses the query parameters that instamojo adds to the RedirectURL
+func ParseRedirectResponse(u url.Values) *RedirectResponse {
+
+	return &RedirectResponse{
+		PaymentID:        u.Get("payment_id"),
+		PaymentStatus:    u.Get("payment_status"),
+		PaymentRequestID: u.Get("payment_request_id"),
+	}
+}
+
+// ConfirmRedirect fetches the payment from Instamojo and checks that it is credited
+// and that it belongs to the payment request mentioned in the redirect.
+// paymentRequestID is the id of the payment request that the caller created for the order the buyer
+// is returning to. The redirect must name the same payment request, Otherwise a buyer could return with
+// a payment that they made for a cheaper payment request. It can only be left empty when the caller
+// matches the payment request of the returned PaymentDetails with its order itself.
+// The returned PaymentDetails is what should be trusted, Not the redirect itself
+func (c *Config) ConfirmRedirect(r *RedirectResponse, paymentRequestID string) (*PaymentDetails, error) {
+	if r.PaymentID == "" {
+		return nil, ErrMissingPaymentID
+	}
+	if r.PaymentRequestID == "" {
+		return nil, ErrMissingPaymentRequestID
+	}
+	if paymentRequestID != "" && r.PaymentRequestID != paymentRequestID {
+		return nil, ErrPaymentRequestMismatch
+	}
+
+	pd, err := c.PaymentDetails(r.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if paymentRequestIDOf(pd) != r.PaymentRequestID {
+		return pd, ErrPaymentRequestMismatch
+	}
+
+	if pd.Payment.Status != "Credit" {
+		return pd, fmt.Errorf("%w: status is %q", ErrPaymentNotCredited, pd.Payment.Status)
+	}
+
+	return pd, nil
+}
+
+// paymentRequestIDOf returns the id of the payment request that a payment belongs to.
+// payment_request is the URL of the payment request in the API response, And the id is its last segment
+func paymentRequestIDOf(pd *PaymentDetails) string {
+	u, err := url.Parse(pd.Payment.PaymentRequest)
+	if err != nil {
+		return ""
+	}
+	p := strings.TrimRight(u.Path, "/")
+	if p == "" {
+		return ""
+	}
+	return path.Base(p)
+}
+
+// RedirectHandler returns a http.Handler that can be mounted at the RedirectURL.
+// It confirms every redirect with ConfirmRedirect against the payment request that expected returns
+// for the request, Usually looked up from the order in the buyer's session.
+// Then it calls success with the confirmed payment or failure with the reason the payment could not be confirmed
+func (c *Config) RedirectHandler(
+	expected func(*http.Request) string,
+	success func(http.ResponseWriter, *http.Request, *PaymentDetails),
+	failure func(http.ResponseWriter, *http.Request, error),
+) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		pd, err := c.ConfirmRedirect(ParseRedirectResponse(req.URL.Query()), expected(req))
+		if err != nil {
+			failure(w, req, err)
+			return
+		}
+
+		success(w, req, pd)
+	})
+}
@@ -0,0 +1,68 @@
+package instamojo_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestRedirectHandler(t *testing.T) {
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/1.1/payments/MOJO5a06005J21512197":
+			fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO5a06005J21512197", "status": "Failed",
+				"payment_request": "https://www.instamojo.com/api/1.1/payment-requests/d66cb29dd059482e8072999f995c4eef/"}}`)
+		default:
+			// A cheap payment that the buyer made for another payment request
+			fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO5a06005J21512198", "status": "Credit",
+				"payment_request": "https://www.instamojo.com/api/1.1/payment-requests/d66cb29dd059482e8072999f995c4eef0/"}}`)
+		}
+	}))
+	defer api.Close()
+
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: api.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got error
+	h := c.RedirectHandler(
+		func(r *http.Request) string {
+			return "d66cb29dd059482e8072999f995c4eef"
+		},
+		func(w http.ResponseWriter, r *http.Request, pd *instamojo.PaymentDetails) {
+			t.Errorf("success called for %s", r.URL.RawQuery)
+		},
+		func(w http.ResponseWriter, r *http.Request, err error) {
+			got = err
+		},
+	)
+
+	tests := []struct {
+		query string
+		want  error
+	}{
+		// The buyer claims the payment was credited, But the API says otherwise
+		{"payment_id=MOJO5a06005J21512197&payment_status=Credit&payment_request_id=d66cb29dd059482e8072999f995c4eef", instamojo.ErrPaymentNotCredited},
+		// The payment of another payment request, Without the payment request
+		{"payment_id=MOJO5a06005J21512198&payment_status=Credit", instamojo.ErrMissingPaymentRequestID},
+		// The payment of another payment request, With a prefix of its id
+		{"payment_id=MOJO5a06005J21512198&payment_status=Credit&payment_request_id=d66cb29dd059482e8072999f995c4eef", instamojo.ErrPaymentRequestMismatch},
+		// The payment of another payment request, With its own id
+		{"payment_id=MOJO5a06005J21512198&payment_status=Credit&payment_request_id=d66cb29dd059482e8072999f995c4eef0", instamojo.ErrPaymentRequestMismatch},
+	}
+
+	for _, tt := range tests {
+		got = nil
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/return?"+tt.query, nil))
+
+		if !errors.Is(got, tt.want) {
+			t.Errorf("%s: Got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package instamojo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOverRefund is returned when a refund is larger than what is left of the payment
+var ErrOverRefund = errors.New("instamojo: refund exceeds the refundable balance")
+
+// RefundBalance is how much of a payment has been refunded so far and how much can still be refunded
+type RefundBalance struct {
+	PaymentID string
+	Amount    Amount
+	Fees      Amount
+	Refunded  Amount
+	Pending   Amount
+	Remaining Amount
+}
+
+// refundFailed has the refund statuses that do not take any money out of a payment
+var refundFailed = map[string]bool{
+	"Cancelled": true,
+	"Failed":    true,
+	"Rejected":  true,
+}
+
+// refundFinal reports whether a refund with status will not change anymore
+func refundFinal(status string) bool {
+	return status == "Refunded" || refundFailed[status]
+}
+
+// RefundableBalance combines PaymentDetails with the refunds already made against the payment.
+// Refunds that are not Refunded yet are counted as Pending, Both are taken out of Remaining
+func (c *Config) RefundableBalance(paymentID string) (*RefundBalance, error) {
+
+	pd, err := c.PaymentDetails(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &RefundBalance{PaymentID: paymentID}
+	if b.Amount, err = ParseAmount(pd.Payment.Amount); err != nil {
+		return nil, err
+	}
+	if pd.Payment.Fees != "" {
+		if b.Fees, err = ParseAmount(pd.Payment.Fees); err != nil {
+			return nil, err
+		}
+	}
+
+	rl, err := c.ListRefunds()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rl.Refunds {
+		if r.PaymentID != paymentID || refundFailed[r.Status] {
+			continue
+		}
+
+		a, err := ParseAmount(r.RefundAmount)
+		if err != nil {
+			return nil, fmt.Errorf("refund %s: %v", r.ID, err)
+		}
+
+		if r.Status == "Refunded" {
+			b.Refunded += a
+		} else {
+			b.Pending += a
+		}
+	}
+
+	b.Remaining = b.Amount - b.Refunded - b.Pending
+	return b, nil
+}
+
+// Check returns ErrOverRefund if amount can not be refunded from the balance
+func (b *RefundBalance) Check(amount Amount) error {
+	if amount <= 0 || amount > b.Remaining {
+		return fmt.Errorf("%w: requested %s, remaining %s", ErrOverRefund, amount, b.Remaining)
+	}
+	return nil
+}
+
+// CreateCheckedRefund is CreateRefundRequest with a RefundableBalance check in front of it,
+// So a refund that would take more than the remaining balance is never sent to instamojo.
+// An empty RefundAmount means a full refund, Like it does in the API
+func (c *Config) CreateCheckedRefund(r *CreateRefundRequest) (*CreateRefundResponse, error) {
+
+	b, err := c.RefundableBalance(r.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := b.Amount
+	if r.RefundAmount != "" {
+		if amount, err = ParseAmount(r.RefundAmount); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := b.Check(amount); err != nil {
+		return nil, err
+	}
+
+	return c.CreateRefundRequest(r)
+}
@@ -0,0 +1,53 @@
+package instamojo_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestCreateCheckedRefund(t *testing.T) {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/1.1/payments/MOJO1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO1", "status": "Credit", "amount": "2500.00", "fees": "125.00"}}`)
+	})
+	mux.HandleFunc("/api/1.1/refunds", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			t.Error("refund request sent for an over refund")
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "refunds": [
+			{"id": "C1", "payment_id": "MOJO1", "status": "Refunded", "refund_amount": "1000.00"},
+			{"id": "C2", "payment_id": "MOJO1", "status": "Pending", "refund_amount": "500.50"},
+			{"id": "C3", "payment_id": "MOJO1", "status": "Cancelled", "refund_amount": "900.00"},
+			{"id": "C4", "payment_id": "MOJO2", "status": "Refunded", "refund_amount": "100.00"}
+		]}`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := c.RefundableBalance("MOJO1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := instamojo.RefundBalance{PaymentID: "MOJO1", Amount: 250000, Fees: 12500, Refunded: 100000, Pending: 50050, Remaining: 99950}
+	if *b != want {
+		t.Errorf("Got %+v, want %+v", *b, want)
+	}
+
+	_, err = c.CreateCheckedRefund(&instamojo.CreateRefundRequest{PaymentID: "MOJO1", Type: "QFL", RefundAmount: "1000.00"})
+	if !errors.Is(err, instamojo.ErrOverRefund) {
+		t.Errorf("Got %v, want %v", err, instamojo.ErrOverRefund)
+	}
+}
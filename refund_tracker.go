@@ -0,0 +1,169 @@
+package instamojo
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// KindOpenRefund is the kind of the records that a RefundTracker keeps its open refunds in
+const KindOpenRefund = "open_refund"
+
+// openRefund is a refund that a RefundTracker is waiting on
+type openRefund struct {
+	ID       string    `json:"id"`
+	Status   string    `json:"status"`
+	Attempts int       `json:"attempts"`
+	NextPoll time.Time `json:"next_poll"`
+}
+
+// RefundTracker polls refunds with RefundDetails until they are Refunded or fail,
+// Because instamojo does not send a webhook when a refund changes status.
+// The open refunds are kept in Store, Which defaults to Config.Store, So tracking survives restarts.
+//
+// A refund is polled MinInterval (1 minute by default) after it is tracked, And the wait doubles
+// after every poll that finds it still open, Up to MaxInterval (6 hours by default).
+// OnDone is called once a refund reaches a final status, Status changes are also published on Config.Events
+type RefundTracker struct {
+	Config      *Config
+	Store       Store
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	OnDone      func(*RefundDetails)
+}
+
+// store returns the Store that the open refunds are kept in, Or an error if neither Store nor Config.Store is set
+func (t *RefundTracker) store() (Store, error) {
+	if t.Store != nil {
+		return t.Store, nil
+	}
+	if t.Config.Store != nil {
+		return t.Config.Store, nil
+	}
+	return nil, fmt.Errorf("instamojo: refund tracker has no store")
+}
+
+func (t *RefundTracker) interval(attempts int) time.Duration {
+	min, max := t.MinInterval, t.MaxInterval
+	if min <= 0 {
+		min = time.Minute
+	}
+	if max <= 0 {
+		max = 6 * time.Hour
+	}
+
+	d := min
+	for i := 0; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// CreateRefund is CreateRefundRequest that also tracks the refund that it creates
+func (t *RefundTracker) CreateRefund(r *CreateRefundRequest) (*CreateRefundResponse, error) {
+	// A refund that could not be tracked should not be created at all
+	if _, err := t.store(); err != nil {
+		return nil, err
+	}
+
+	crr, err := t.Config.CreateRefundRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return crr, t.track(crr.Refund.ID, crr.Refund.Status)
+}
+
+// Track starts polling refundID
+func (t *RefundTracker) Track(refundID string) error {
+	return t.track(refundID, "")
+}
+
+func (t *RefundTracker) track(refundID, status string) error {
+	store, err := t.store()
+	if err != nil {
+		return err
+	}
+
+	return store.Put(KindOpenRefund, refundID, openRefund{
+		ID:       refundID,
+		Status:   status,
+		NextPoll: time.Now().Add(t.interval(0)),
+	})
+}
+
+// PollDue polls the open refunds that are due at now.
+// It keeps going when a lookup fails and returns the first error
+func (t *RefundTracker) PollDue(now time.Time) error {
+	store, err := t.store()
+	if err != nil {
+		return err
+	}
+
+	ids, err := store.List(KindOpenRefund)
+	if err != nil {
+		return err
+	}
+
+	var first error
+	for _, id := range ids {
+		if err := t.poll(store, id, now); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (t *RefundTracker) poll(store Store, id string, now time.Time) error {
+	open := openRefund{}
+	ok, err := store.Get(KindOpenRefund, id, &open)
+	if err != nil || !ok || open.NextPoll.After(now) {
+		return err
+	}
+
+	rd, err := t.Config.RefundDetails(id)
+	if err != nil {
+		return err
+	}
+
+	if rd.Refund.Status != open.Status {
+		t.Config.publish(Event{Type: EventRefundStatusChanged, ID: id, Status: rd.Refund.Status, Data: rd})
+	}
+
+	if refundFinal(rd.Refund.Status) {
+		if err := store.Delete(KindOpenRefund, id); err != nil {
+			return err
+		}
+		if t.OnDone != nil {
+			t.OnDone(rd)
+		}
+		return nil
+	}
+
+	open.Status = rd.Refund.Status
+	open.Attempts++
+	open.NextPoll = now.Add(t.interval(open.Attempts))
+	return store.Put(KindOpenRefund, id, open)
+}
+
+// Run calls PollDue every MinInterval until ctx is done.
+// Errors from PollDue are passed to onError if it is not nil
+func (t *RefundTracker) Run(ctx context.Context, onError func(error)) error {
+	tick := time.NewTicker(t.interval(0))
+	defer tick.Stop()
+
+	for {
+		if err := t.PollDue(time.Now()); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package instamojo_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestRefundTracker(t *testing.T) {
+
+	status := "Pending"
+	polls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		fmt.Fprintf(w, `{"success": true, "refund": {"id": "C1", "payment_id": "MOJO1", "status": %q}}`, status)
+	}))
+	defer ts.Close()
+
+	bare, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	untracked := &instamojo.RefundTracker{Config: bare}
+	if err := untracked.Track("C1"); err == nil {
+		t.Error("tracked a refund without a store")
+	}
+	if err := untracked.PollDue(time.Now()); err == nil {
+		t.Error("polled refunds without a store")
+	}
+	if _, err := untracked.CreateRefund(&instamojo.CreateRefundRequest{PaymentID: "MOJO1"}); err == nil {
+		t.Error("created a refund without a store")
+	}
+	if polls != 0 {
+		t.Errorf("Sent %d requests without a store", polls)
+	}
+
+	store := instamojo.NewMemoryStore()
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: ts.URL, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var done []string
+	tracker := &instamojo.RefundTracker{
+		Config:      c,
+		MinInterval: time.Minute,
+		OnDone: func(rd *instamojo.RefundDetails) {
+			done = append(done, rd.Refund.ID+" "+rd.Refund.Status)
+		},
+	}
+	if err := tracker.Track("C1"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	steps := []struct {
+		after time.Duration
+		polls int
+	}{
+		{after: 30 * time.Second, polls: 0}, // not due yet
+		{after: 2 * time.Minute, polls: 1},  // first poll, next one in 2 minutes
+		{after: 3 * time.Minute, polls: 1},  // backing off
+		{after: 5 * time.Minute, polls: 2},
+	}
+	for _, s := range steps {
+		if err := tracker.PollDue(now.Add(s.after)); err != nil {
+			t.Fatal(err)
+		}
+		if polls != s.polls {
+			t.Fatalf("after %v: Got %d polls, want %d", s.after, polls, s.polls)
+		}
+	}
+
+	// A restarted tracker picks the refund up from the store
+	status = "Refunded"
+	tracker = &instamojo.RefundTracker{Config: c, OnDone: tracker.OnDone}
+	if err := tracker.PollDue(now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(done) != 1 || done[0] != "C1 Refunded" {
+		t.Errorf("Got %q, want C1 Refunded", done)
+	}
+	if ids, _ := store.List(instamojo.KindOpenRefund); len(ids) != 0 {
+		t.Errorf("Got open refunds %q after completion", ids)
+	}
+}
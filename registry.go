@@ -0,0 +1,173 @@
+package instamojo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// MerchantProvider looks up the Settings of a merchant by its id.
+// It should return an error that wraps ErrUnknownMerchant when there is no such merchant
+type MerchantProvider interface {
+	Merchant(id string) (Settings, error)
+}
+
+// MerchantProviderFunc is a function that is a MerchantProvider
+type MerchantProviderFunc func(id string) (Settings, error)
+
+// Merchant calls f
+func (f MerchantProviderFunc) Merchant(id string) (Settings, error) {
+	return f(id)
+}
+
+// ErrUnknownMerchant is returned when a MerchantProvider has no merchant with an id
+var ErrUnknownMerchant = errors.New("instamojo: unknown merchant")
+
+// RateLimit is a Middleware that spaces the requests that pass through it by at least interval.
+// A request waits for its turn until its context is done
+func RateLimit(interval time.Duration) Middleware {
+	var (
+		mu   sync.Mutex
+		next time.Time
+	)
+
+	return func(rt RoundTripFunc) RoundTripFunc {
+		return func(op Operation, req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			now := time.Now()
+			at := next
+			if at.Before(now) {
+				at = now
+			}
+			next = at.Add(interval)
+			mu.Unlock()
+
+			if wait := at.Sub(now); wait > 0 {
+				t := time.NewTimer(wait)
+				select {
+				case <-t.C:
+				case <-req.Context().Done():
+					t.Stop()
+					return nil, req.Context().Err()
+				}
+			}
+			return rt(op, req)
+		}
+	}
+}
+
+// Registry holds one Client per merchant of a marketplace.
+// Clients are created from the Settings that Provider returns the first time a merchant is used,
+// And every Client has its own credentials, private salt and rate limiter.
+// Options are applied to every Client after its Settings, Passing WithHTTPClient here makes all
+// the merchants share one pool of connections. Interval is the minimum time between two requests of the same merchant
+type Registry struct {
+	Provider MerchantProvider
+	Options  []Option
+	Interval time.Duration
+
+	mu      sync.Mutex
+	clients map[string]*registryEntry
+}
+
+type registryEntry struct {
+	ready chan struct{}
+	cl    *Client
+	err   error
+}
+
+// NewRegistry creates an empty Registry that loads merchants from p
+func NewRegistry(p MerchantProvider, interval time.Duration, opts ...Option) *Registry {
+	return &Registry{Provider: p, Interval: interval, Options: opts, clients: make(map[string]*registryEntry)}
+}
+
+// Client returns the Client of merchantID, Creating it if this is the first time it is used.
+// Concurrent calls for the same merchant share one lookup, A lookup that fails is tried again on the next call
+func (r *Registry) Client(merchantID string) (*Client, error) {
+	r.mu.Lock()
+	if r.clients == nil {
+		r.clients = make(map[string]*registryEntry)
+	}
+	if e, ok := r.clients[merchantID]; ok {
+		r.mu.Unlock()
+		<-e.ready
+		return e.cl, e.err
+	}
+
+	e := &registryEntry{ready: make(chan struct{})}
+	r.clients[merchantID] = e
+	r.mu.Unlock()
+
+	e.cl, e.err = r.load(merchantID)
+	if e.err != nil {
+		r.mu.Lock()
+		delete(r.clients, merchantID)
+		r.mu.Unlock()
+	}
+	close(e.ready)
+
+	return e.cl, e.err
+}
+
+func (r *Registry) load(merchantID string) (*Client, error) {
+	s, err := r.Provider.Merchant(merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("error in loading merchant %s: %w", merchantID, err)
+	}
+	if err := s.validate("merchant " + merchantID); err != nil {
+		return nil, err
+	}
+
+	opts := append(s.options(), r.Options...)
+	if r.Interval > 0 {
+		opts = append(opts, WithMiddleware(RateLimit(r.Interval)))
+	}
+	return NewClient(s.APIKey, s.AuthToken, opts...)
+}
+
+// Remove forgets the Client of merchantID, So the next call to Client loads it again.
+// It is meant for merchants whose credentials changed or that left the marketplace
+func (r *Registry) Remove(merchantID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.clients, merchantID)
+}
+
+// WebhookHandler returns a http.Handler that receives the webhooks of all the merchants.
+// Instamojo does not say which merchant a webhook is for, So the merchant id has to be part of the
+// webhook URL that was set when the payment request was created, Like /webhooks/<merchant id>.
+// merchant extracts it from the request. The webhook is verified with the private salt of that
+// merchant and handled by its Client like Config.WebhookHandler does, Then passed to fn.
+// It answers 404 to webhooks for merchants that the Provider does not know, 503 when the merchant
+// could not be loaded for any other reason so that instamojo sends the webhook again,
+// And 400 to the ones for merchants without a private salt
+func (r *Registry) WebhookHandler(merchant func(*http.Request) string, fn func(merchantID string, w *WebhookResponse)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := merchant(req)
+		if id == "" {
+			http.Error(w, "unknown merchant", http.StatusNotFound)
+			return
+		}
+
+		cl, err := r.Client(id)
+		if errors.Is(err, ErrUnknownMerchant) {
+			http.Error(w, "unknown merchant", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "merchant could not be loaded", http.StatusServiceUnavailable)
+			return
+		}
+
+		if cl.config.PrivateSalt == "" {
+			http.Error(w, "merchant has no private salt", http.StatusBadRequest)
+			return
+		}
+
+		cl.config.WebhookHandler("", func(wr *WebhookResponse) {
+			fn(id, wr)
+		}).ServeHTTP(w, req)
+	})
+}
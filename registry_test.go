@@ -0,0 +1,128 @@
+package instamojo_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestRegistry(t *testing.T) {
+
+	var mu sync.Mutex
+	keys := map[string]bool{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys[r.Header.Get("X-Api-Key")] = true
+		mu.Unlock()
+		fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO1", "status": "Credit"}}`)
+	}))
+	defer ts.Close()
+
+	var lookups int32
+	provider := instamojo.MerchantProviderFunc(func(id string) (instamojo.Settings, error) {
+		atomic.AddInt32(&lookups, 1)
+		if id == "unknown" {
+			return instamojo.Settings{}, instamojo.ErrUnknownMerchant
+		}
+		return instamojo.Settings{APIKey: id + "-key", AuthToken: id + "-token", PrivateSalt: id + "-salt", BaseURL: ts.URL}, nil
+	})
+
+	reg := instamojo.NewRegistry(provider, 20*time.Millisecond, instamojo.WithHTTPClient(ts.Client()))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := reg.Client("alpha"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&lookups); n != 1 {
+		t.Errorf("Looked up the merchant %d times", n)
+	}
+
+	if _, err := reg.Client("unknown"); !errors.Is(err, instamojo.ErrUnknownMerchant) {
+		t.Errorf("Got error %v", err)
+	}
+
+	alpha, _ := reg.Client("alpha")
+	beta, err := reg.Client("beta")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every merchant is rate limited on its own
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := alpha.PaymentDetails("MOJO1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("3 requests took %v", d)
+	}
+	if _, err := beta.PaymentDetails("MOJO1"); err != nil {
+		t.Fatal(err)
+	}
+	if !keys["alpha-key"] || !keys["beta-key"] {
+		t.Errorf("Got api keys %v", keys)
+	}
+}
+
+func TestRegistryWebhookHandler(t *testing.T) {
+
+	reg := instamojo.NewRegistry(instamojo.MerchantProviderFunc(func(id string) (instamojo.Settings, error) {
+		if id == "down" {
+			return instamojo.Settings{}, errors.New("database is down")
+		}
+		if id != "alpha" && id != "beta" {
+			return instamojo.Settings{}, instamojo.ErrUnknownMerchant
+		}
+		return instamojo.Settings{APIKey: "key", AuthToken: "token", PrivateSalt: id + "-salt"}, nil
+	}), 0)
+
+	var got []string
+	ts := httptest.NewServer(reg.WebhookHandler(func(r *http.Request) string {
+		return strings.TrimPrefix(r.URL.Path, "/webhooks/")
+	}, func(merchantID string, w *instamojo.WebhookResponse) {
+		got = append(got, merchantID+"/"+w.PaymentID)
+	}))
+	defer ts.Close()
+
+	w := &instamojo.WebhookResponse{PaymentID: "MOJO1", Status: "Credit"}
+	tests := []struct {
+		merchant, salt string
+		status         int
+	}{
+		{"alpha", "alpha-salt", 200},
+		{"beta", "beta-salt", 200},
+		{"beta", "alpha-salt", 400},
+		{"gamma", "gamma-salt", 404},
+		{"down", "down-salt", 503},
+	}
+	for _, tt := range tests {
+		resp, err := instamojo.PostWebhook(ts.URL+"/webhooks/"+tt.merchant, w, tt.salt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s signed with %s: Got status %d", tt.merchant, tt.salt, resp.StatusCode)
+		}
+	}
+
+	if strings.Join(got, ",") != "alpha/MOJO1,beta/MOJO1" {
+		t.Errorf("Got webhooks %v", got)
+	}
+}
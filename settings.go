@@ -0,0 +1,161 @@
+package instamojo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Settings is the configuration of a Client as it is read from the environment or a file
+type Settings struct {
+	APIKey      string `json:"api_key" yaml:"api_key" toml:"api_key"`
+	AuthToken   string `json:"auth_token" yaml:"auth_token" toml:"auth_token"`
+	Sandbox     bool   `json:"sandbox" yaml:"sandbox" toml:"sandbox"`
+	PrivateSalt string `json:"private_salt" yaml:"private_salt" toml:"private_salt"`
+	BaseURL     string `json:"base_url" yaml:"base_url" toml:"base_url"`
+}
+
+// ConfigError lists everything that is wrong with the Settings read from Source
+type ConfigError struct {
+	Source   string
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("instamojo: invalid config in %s: %s", e.Source, strings.Join(e.Problems, "; "))
+}
+
+// validate returns a ConfigError with all the problems in s, Or nil if there are none
+func (s Settings) validate(source string) error {
+	var problems []string
+	if s.APIKey == "" {
+		problems = append(problems, "api_key is required")
+	}
+	if s.AuthToken == "" {
+		problems = append(problems, "auth_token is required")
+	}
+	if s.BaseURL != "" {
+		if u, err := url.Parse(s.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("base_url %q is not an absolute url", s.BaseURL))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Source: source, Problems: problems}
+	}
+	return nil
+}
+
+// options turns s into the Options of a Client
+func (s Settings) options() []Option {
+	opts := []Option{WithPrivateSalt(s.PrivateSalt)}
+	if s.Sandbox {
+		opts = append(opts, WithSandbox())
+	}
+	if s.BaseURL != "" {
+		opts = append(opts, WithBaseURL(s.BaseURL))
+	}
+	return opts
+}
+
+// SettingsFromEnv reads Settings from INSTAMOJO_API_KEY, INSTAMOJO_AUTH_TOKEN, INSTAMOJO_SANDBOX,
+// INSTAMOJO_PRIVATE_SALT and INSTAMOJO_BASE_URL. INSTAMOJO_SANDBOX takes the values that strconv.ParseBool does
+func SettingsFromEnv() (Settings, error) {
+	s := Settings{
+		APIKey:      os.Getenv("INSTAMOJO_API_KEY"),
+		AuthToken:   os.Getenv("INSTAMOJO_AUTH_TOKEN"),
+		PrivateSalt: os.Getenv("INSTAMOJO_PRIVATE_SALT"),
+		BaseURL:     os.Getenv("INSTAMOJO_BASE_URL"),
+	}
+
+	err := s.validate("environment")
+	if v := os.Getenv("INSTAMOJO_SANDBOX"); v != "" {
+		sandbox, perr := strconv.ParseBool(v)
+		if perr != nil {
+			if err == nil {
+				err = &ConfigError{Source: "environment"}
+			}
+			ce := err.(*ConfigError)
+			ce.Problems = append(ce.Problems, fmt.Sprintf("INSTAMOJO_SANDBOX %q is not a boolean", v))
+		}
+		s.Sandbox = sandbox
+	}
+
+	return s, err
+}
+
+// NewClientFromEnv creates a Client from SettingsFromEnv, opts are applied after the settings
+func NewClientFromEnv(opts ...Option) (*Client, error) {
+	s, err := SettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(s.APIKey, s.AuthToken, append(s.options(), opts...)...)
+}
+
+// settingsFile is the layout of the files read by LoadSettings
+type settingsFile struct {
+	Profiles map[string]Settings `json:"profiles" yaml:"profiles" toml:"profiles"`
+}
+
+// LoadSettings reads the Settings of a profile from a file with several named profiles.
+// The format is picked by the extension of path, .json, .yaml, .yml or .toml.
+// An empty profile means the profile named "default". In JSON the file looks like
+//
+//	{"profiles": {"default": {"api_key": "...", "auth_token": "...", "sandbox": true}}}
+//
+// In YAML it is a "profiles" mapping with one mapping per profile, And in TOML a [profiles.<name>] table per profile
+func LoadSettings(path, profile string) (Settings, error) {
+	if profile == "" {
+		profile = "default"
+	}
+	source := fmt.Sprintf("%s (profile %q)", path, profile)
+
+	var unmarshal func([]byte, interface{}) error
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		unmarshal = json.Unmarshal
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	case ".toml":
+		unmarshal = toml.Unmarshal
+	default:
+		return Settings{}, fmt.Errorf("instamojo: unsupported config format %q", ext)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	f := settingsFile{}
+	if err := unmarshal(b, &f); err != nil {
+		return Settings{}, fmt.Errorf("instamojo: error in reading %s: %v", path, err)
+	}
+
+	s, ok := f.Profiles[profile]
+	if !ok {
+		return Settings{}, &ConfigError{Source: source, Problems: []string{"no such profile"}}
+	}
+	return s, s.validate(source)
+}
+
+// NewClientFromFile creates a Client from the Settings of profile in the file at path,
+// opts are applied after the settings
+func NewClientFromFile(path, profile string, opts ...Option) (*Client, error) {
+	s, err := LoadSettings(path, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(s.APIKey, s.AuthToken, append(s.options(), opts...)...)
+}
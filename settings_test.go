@@ -0,0 +1,128 @@
+package instamojo_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestSettingsFromEnv(t *testing.T) {
+
+	t.Setenv("INSTAMOJO_API_KEY", "key")
+	t.Setenv("INSTAMOJO_AUTH_TOKEN", "token")
+	t.Setenv("INSTAMOJO_SANDBOX", "true")
+	t.Setenv("INSTAMOJO_PRIVATE_SALT", "salt")
+	t.Setenv("INSTAMOJO_BASE_URL", "")
+
+	s, err := instamojo.SettingsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.APIKey != "key" || s.AuthToken != "token" || !s.Sandbox || s.PrivateSalt != "salt" {
+		t.Errorf("Got %+v", s)
+	}
+
+	cl, err := instamojo.NewClientFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := cl.Config(); !c.SandboxMode || c.PrivateSalt != "salt" {
+		t.Errorf("Got config %+v", c)
+	}
+
+	t.Setenv("INSTAMOJO_API_KEY", "")
+	t.Setenv("INSTAMOJO_SANDBOX", "maybe")
+	t.Setenv("INSTAMOJO_BASE_URL", "example.com")
+
+	_, err = instamojo.SettingsFromEnv()
+	var ce *instamojo.ConfigError
+	if !errors.As(err, &ce) {
+		t.Fatalf("Got error %v", err)
+	}
+	if len(ce.Problems) != 3 {
+		t.Errorf("Got problems %q", ce.Problems)
+	}
+}
+
+func TestLoadSettings(t *testing.T) {
+
+	files := map[string]string{
+		"settings.json": `{"profiles": {
+			"default": {"api_key": "key", "auth_token": "token", "sandbox": true, "private_salt": "salt"},
+			"live": {"api_key": "live-key", "auth_token": "live-token", "base_url": "https://api.example.com/"}
+		}}`,
+		"settings.yaml": `
+# services read their profile from here
+profiles:
+  default:
+    api_key: key
+    auth_token: "token"
+    sandbox: true
+    private_salt: 'salt' # from the dashboard
+  live:
+    api_key: live-key
+    auth_token: live-token
+    base_url: https://api.example.com/
+`,
+		"settings.toml": `
+[profiles.default]
+api_key = "key"
+auth_token = "token"
+sandbox = true
+private_salt = "salt" # from the dashboard
+
+[profiles.live]
+api_key = "live-key"
+auth_token = "live-token"
+base_url = "https://api.example.com/"
+`,
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := instamojo.LoadSettings(path, "")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := instamojo.Settings{APIKey: "key", AuthToken: "token", Sandbox: true, PrivateSalt: "salt"}
+		if s != want {
+			t.Errorf("%s: Got %+v", name, s)
+		}
+
+		cl, err := instamojo.NewClientFromFile(path, "live")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if c := cl.Config(); c.BaseURL != "https://api.example.com/" || c.APIKey != "live-key" {
+			t.Errorf("%s: Got config %+v", name, c)
+		}
+
+		_, err = instamojo.LoadSettings(path, "staging")
+		var ce *instamojo.ConfigError
+		if !errors.As(err, &ce) || !strings.Contains(err.Error(), "staging") {
+			t.Errorf("%s: Got error %v for a missing profile", name, err)
+		}
+	}
+
+	path := filepath.Join(dir, "invalid.json")
+	if err := ioutil.WriteFile(path, []byte(`{"profiles": {"default": {"api_key": "key"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := instamojo.LoadSettings(path, "default")
+	if err == nil || !strings.Contains(err.Error(), "auth_token is required") {
+		t.Errorf("Got error %v", err)
+	}
+
+	if _, err := instamojo.LoadSettings(filepath.Join(dir, "settings.ini"), ""); err == nil {
+		t.Error("loaded a file in an unsupported format")
+	}
+}
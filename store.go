@@ -0,0 +1,245 @@
+package instamojo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kinds of records that Config saves in a Store
+const (
+	KindPaymentRequest = "payment_request"
+	KindPayment        = "payment"
+	KindRefund         = "refund"
+	KindWebhook        = "webhook"
+)
+
+// Store persists records by their kind and id. Put replaces any record with the same kind and id.
+// Records are JSON encoded, So Get can decode them into any type that they were saved from.
+// Implementations must be safe for concurrent use
+type Store interface {
+	Put(kind, id string, v interface{}) error
+	Get(kind, id string, v interface{}) (bool, error)
+	List(kind string) ([]string, error)
+	Delete(kind, id string) error
+}
+
+// save writes v to c.Store if there is one.
+// The API call that produced v has already succeeded, So errors go to c.OnStoreError instead of the caller
+func (c *Config) save(kind, id string, v interface{}) {
+	if c.Store == nil {
+		return
+	}
+
+	if err := c.Store.Put(kind, id, v); err != nil && c.OnStoreError != nil {
+		c.OnStoreError(fmt.Errorf("error in saving %s %s: %v", kind, id, err))
+	}
+}
+
+// ErrInvalidMAC is returned for a webhook whose mac does not match, Or when there is no private salt to check it with
+var ErrInvalidMAC = errors.New("instamojo: invalid webhook mac")
+
+// ParseWebhookResponse is ParseWebhookResponse that first verifies the mac of the webhook with c.PrivateSalt,
+// Then saves it in c.Store and publishes it on c.Events. A webhook that can not be verified returns ErrInvalidMAC
+// and is neither saved nor published, So a forged webhook can not get into the audit trail or fire events
+func (c *Config) ParseWebhookResponse(u url.Values) (*WebhookResponse, error) {
+	if c.PrivateSalt == "" || !VerifyWebhookMAC(u, c.PrivateSalt) {
+		return nil, ErrInvalidMAC
+	}
+
+	w := ParseWebhookResponse(u)
+	c.recordWebhook(w)
+	return w, nil
+}
+
+// recordWebhook saves and publishes a webhook whose mac has been verified
+func (c *Config) recordWebhook(w *WebhookResponse) {
+	c.save(KindWebhook, w.PaymentID, w)
+	if c.Events != nil {
+		c.Events.PublishWebhook(w)
+	}
+}
+
+// MemoryStore is a Store that keeps the records in memory
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]map[string][]byte
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]map[string][]byte)}
+}
+
+// Put saves v as the record kind/id
+func (m *MemoryStore) Put(kind, id string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.records[kind] == nil {
+		m.records[kind] = make(map[string][]byte)
+	}
+	m.records[kind][id] = b
+	return nil
+}
+
+// Get decodes the record kind/id into v, It reports false if there is no such record
+func (m *MemoryStore) Get(kind, id string, v interface{}) (bool, error) {
+	m.mu.RLock()
+	b, ok := m.records[kind][id]
+	m.mu.RUnlock()
+
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(b, v)
+}
+
+// List returns the sorted ids of all the records of kind
+func (m *MemoryStore) List(kind string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.records[kind]))
+	for id := range m.records[kind] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Delete removes the record kind/id if it exists
+func (m *MemoryStore) Delete(kind, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records[kind], id)
+	return nil
+}
+
+// FileStore is a Store that keeps every record in its own JSON file, At Dir/kind/id.json
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore creates a FileStore in dir, Creating dir if it does not exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+func (f *FileStore) path(kind, id string) string {
+	return filepath.Join(f.Dir, url.PathEscape(kind), url.PathEscape(id)+".json")
+}
+
+// Put saves v as the record kind/id, See writeFileAtomic
+func (f *FileStore) Put(kind, id string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	p := f.path(kind, id)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(p, b)
+}
+
+// writeFileAtomic replaces the file at path with b.
+// b is written to a temporary file in the same directory and synced to disk before it is renamed over path,
+// So after a crash path has either the old or the new content and never a part of it.
+// The directory is synced too, So the rename itself is not lost
+func writeFileAtomic(path string, b []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, ".tmp-"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Get decodes the record kind/id into v, It reports false if there is no such record
+func (f *FileStore) Get(kind, id string, v interface{}) (bool, error) {
+	b, err := ioutil.ReadFile(f.path(kind, id))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(b, v)
+}
+
+// List returns the sorted ids of all the records of kind
+func (f *FileStore) List(kind string) ([]string, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(f.Dir, url.PathEscape(kind)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".tmp-") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		id, err := url.PathUnescape(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Delete removes the record kind/id if it exists
+func (f *FileStore) Delete(kind, id string) error {
+	err := os.Remove(f.path(kind, id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package instamojo_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestStore(t *testing.T) {
+
+	fs, err := instamojo.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]instamojo.Store{
+		"memory": instamojo.NewMemoryStore(),
+		"file":   fs,
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success": true, "payment": {"payment_id": "MOJO1", "status": "Credit", "amount": "10.00"}}`)
+	}))
+	defer ts.Close()
+
+	for name, store := range stores {
+		c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: ts.URL, Store: store, PrivateSalt: "salt"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := c.PaymentDetails("MOJO1"); err != nil {
+			t.Fatal(err)
+		}
+		// A forged webhook without a mac is not saved
+		if _, err := c.ParseWebhookResponse(url.Values{"payment_id": []string{"MOJO2"}, "status": []string{"Credit"}}); !errors.Is(err, instamojo.ErrInvalidMAC) {
+			t.Errorf("%s: Got error %v for a webhook without a mac", name, err)
+		}
+		if _, err := c.ParseWebhookResponse(instamojo.SignWebhookResponse(&instamojo.WebhookResponse{PaymentID: "MOJO1/x", Status: "Credit"}, "salt")); err != nil {
+			t.Fatal(err)
+		}
+
+		pd := &instamojo.PaymentDetails{}
+		ok, err := store.Get(instamojo.KindPayment, "MOJO1", pd)
+		if err != nil || !ok {
+			t.Fatalf("%s: Get = %v, %v", name, ok, err)
+		}
+		if pd.Payment.Amount != "10.00" {
+			t.Errorf("%s: Got amount %q, want 10.00", name, pd.Payment.Amount)
+		}
+
+		ids, err := store.List(instamojo.KindWebhook)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids, []string{"MOJO1/x"}) {
+			t.Errorf("%s: Got webhooks %q", name, ids)
+		}
+
+		if err := store.Delete(instamojo.KindWebhook, "MOJO1/x"); err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := store.Get(instamojo.KindWebhook, "MOJO1/x", &instamojo.WebhookResponse{}); ok {
+			t.Errorf("%s: deleted record still exists", name)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package instamojo
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// KindCheckpoint is the kind of the records that a Syncer keeps its progress in
+const KindCheckpoint = "checkpoint"
+
+// Change is a record that a Syncer found to be new or modified, Record is what was saved in the Store
+type Change struct {
+	Kind   string
+	ID     string
+	Record interface{}
+}
+
+// syncCheckpoint is how far a Syncer has got, Everything modified before ModifiedAt has been synced.
+// Until is only set while a sync is running, So an interrupted sync resumes from the window it was on
+type syncCheckpoint struct {
+	ModifiedAt time.Time `json:"modified_at"`
+	Until      time.Time `json:"until"`
+}
+
+// syncEpoch is older than any payment request, The first Sync starts from it
+var syncEpoch = time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Syncer copies the payment requests and their payments into a Store incrementally.
+// Every Sync only pulls the payment requests that were modified since the last one,
+// Expands them with PaymentRequestDetails and saves the payment request and each of its payments.
+// Store defaults to Config.Store and PageSize to 50. OnChange, If it is set, Is called with every saved record
+type Syncer struct {
+	Config   *Config
+	Store    Store
+	PageSize int
+	OnChange func(Change)
+
+	// Name tells apart the checkpoints of Syncers that share a Store
+	Name string
+}
+
+// Sync pulls the changes since the last checkpoint.
+// It walks forward from the checkpoint with a window of modification times that is narrowed until it fits on one page,
+// Instead of paging through a fixed window, Because a payment request that is modified during the sync leaves the
+// window and would shift the later ones to an earlier page. Such a payment request is pulled by the next Sync.
+// It saves the checkpoint after every window, So a Sync that fails can be called again and it continues where it stopped
+func (s *Syncer) Sync() error {
+	store := s.Store
+	if store == nil {
+		store = s.Config.Store
+	}
+	if store == nil {
+		return fmt.Errorf("instamojo: syncer has no store")
+	}
+	limit := s.PageSize
+	if limit < 1 {
+		limit = 50
+	}
+	name := "payment_requests"
+	if s.Name != "" {
+		name = s.Name
+	}
+
+	cp := syncCheckpoint{}
+	if _, err := store.Get(KindCheckpoint, name, &cp); err != nil {
+		return err
+	}
+	if cp.ModifiedAt.IsZero() {
+		cp.ModifiedAt = syncEpoch
+	}
+	if cp.Until.IsZero() {
+		cp.Until = time.Now().UTC().Truncate(time.Second)
+	}
+
+	// span is the size of the next window, It doubles after every window so that quiet periods are skipped quickly
+	span := cp.Until.Sub(cp.ModifiedAt)
+	for cp.ModifiedAt.Before(cp.Until) {
+		to := cp.ModifiedAt.Add(span)
+		if span <= 0 || to.After(cp.Until) {
+			to = cp.Until
+		}
+
+		requests, to, err := s.window(cp.ModifiedAt, to, limit)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range requests {
+			if err := s.syncRequest(store, id.ID, id.ModifiedAt); err != nil {
+				return err
+			}
+		}
+
+		span = 2 * to.Sub(cp.ModifiedAt)
+		cp.ModifiedAt = to
+		if err := store.Put(KindCheckpoint, name, cp); err != nil {
+			return err
+		}
+	}
+
+	return store.Put(KindCheckpoint, name, syncCheckpoint{ModifiedAt: cp.Until})
+}
+
+// syncRef is a payment request found in a window
+type syncRef struct {
+	ID         string
+	ModifiedAt time.Time
+}
+
+// window lists the payment requests modified between from and to, Halving the window until they fit on one page.
+// It returns them and where the window it listed ends.
+// Windows are whole seconds, More than a page of changes within one second is paged through instead
+func (s *Syncer) window(from, to time.Time, limit int) ([]syncRef, time.Time, error) {
+	for {
+		rl, err := s.Config.ListRequestsWithOptions(&ListRequestsOptions{MinModifiedAt: from, MaxModifiedAt: to, Page: 1, Limit: limit})
+		if err != nil {
+			return nil, to, err
+		}
+
+		mid := from.Add(to.Sub(from) / 2).Truncate(time.Second)
+		if len(rl.PaymentRequests) < limit || !mid.After(from) {
+			refs := make([]syncRef, 0, len(rl.PaymentRequests))
+			for page := 1; ; page++ {
+				for _, pr := range rl.PaymentRequests {
+					refs = append(refs, syncRef{ID: pr.ID, ModifiedAt: pr.ModifiedAt})
+				}
+				if len(rl.PaymentRequests) < limit {
+					return refs, to, nil
+				}
+
+				rl, err = s.Config.ListRequestsWithOptions(&ListRequestsOptions{MinModifiedAt: from, MaxModifiedAt: to, Page: page + 1, Limit: limit})
+				if err != nil {
+					return nil, to, err
+				}
+			}
+		}
+		to = mid
+	}
+}
+
+// syncRequest saves a payment request and its payments, Unless the stored copy is already up to date
+func (s *Syncer) syncRequest(store Store, id string, modifiedAt time.Time) error {
+	old := &PaymentRequestDetails{}
+	ok, err := store.Get(KindPaymentRequest, id, old)
+	if err != nil {
+		return err
+	}
+	if ok && old.PaymentRequest.ModifiedAt.Equal(modifiedAt) {
+		return nil
+	}
+
+	prd, err := s.Config.PaymentRequestDetails(id)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range prd.PaymentRequest.Payments {
+		// Save payments in the same shape that PaymentDetails returns them
+		pd := &PaymentDetails{Success: true}
+		b, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &pd.Payment); err != nil {
+			return err
+		}
+
+		if err := s.put(store, KindPayment, p.PaymentID, pd); err != nil {
+			return err
+		}
+	}
+
+	return s.put(store, KindPaymentRequest, id, prd)
+}
+
+func (s *Syncer) put(store Store, kind, id string, v interface{}) error {
+	if err := store.Put(kind, id, v); err != nil {
+		return err
+	}
+
+	if s.OnChange != nil {
+		s.OnChange(Change{Kind: kind, ID: id, Record: v})
+	}
+	return nil
+}
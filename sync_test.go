@@ -0,0 +1,144 @@
+package instamojo_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+// syncServer serves payment requests like instamojo does, Filtered by min_modified_at and max_modified_at
+// and paged, But newest first. onDetails is called before the details of a payment request are served
+func syncServer(t *testing.T, requests map[string]time.Time, mu *sync.Mutex, onDetails func(id string)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/1.1/payment-requests"), "/")
+		if id != "" {
+			if onDetails != nil {
+				onDetails(id)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"payment_request": map[string]interface{}{
+					"id": id, "status": "Completed", "modified_at": requests[id],
+					"payments": []map[string]string{{"payment_id": "MOJO-" + id, "status": "Credit"}},
+				},
+			})
+			return
+		}
+
+		q := r.URL.Query()
+		min, _ := time.Parse(time.RFC3339, q.Get("min_modified_at"))
+		max, err := time.Parse(time.RFC3339, q.Get("max_modified_at"))
+		if err != nil {
+			t.Errorf("Got no max_modified_at in %s", r.URL.RawQuery)
+		}
+		page, _ := strconv.Atoi(q.Get("page"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+
+		mu.Lock()
+		type request struct {
+			ID         string    `json:"id"`
+			ModifiedAt time.Time `json:"modified_at"`
+		}
+		var matched []request
+		for id, at := range requests {
+			if !at.Before(min) && !at.After(max) {
+				matched = append(matched, request{ID: id, ModifiedAt: at})
+			}
+		}
+		mu.Unlock()
+		sort.Slice(matched, func(i, j int) bool { return matched[i].ModifiedAt.After(matched[j].ModifiedAt) })
+
+		start, end := (page-1)*limit, page*limit
+		if start > len(matched) {
+			start = len(matched)
+		}
+		if end > len(matched) {
+			end = len(matched)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "payment_requests": matched[start:end]})
+	}))
+}
+
+func TestSyncer(t *testing.T) {
+
+	base := time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	requests := map[string]time.Time{
+		"PR1": base,
+		"PR2": base.Add(time.Hour),
+		"PR3": base.Add(2 * time.Hour),
+		"PR4": base.Add(3 * time.Hour),
+		"PR5": base.Add(4 * time.Hour),
+	}
+
+	// PR1 is modified while the sync is running, Which moves it out of the window
+	modified := false
+	ts := syncServer(t, requests, &mu, func(id string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if id == "PR1" && !modified {
+			modified = true
+			requests["PR1"] = time.Now().UTC().Add(time.Hour)
+		}
+	})
+	defer ts.Close()
+
+	c, err := instamojo.Init(&instamojo.Config{APIKey: "key", AuthToken: "token", BaseURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (&instamojo.Syncer{Config: c}).Sync(); err == nil {
+		t.Error("synced without a store")
+	}
+
+	changes := map[string]int{}
+	s := &instamojo.Syncer{
+		Config:   c,
+		Store:    instamojo.NewMemoryStore(),
+		PageSize: 2,
+		OnChange: func(ch instamojo.Change) {
+			changes[ch.Kind+":"+ch.ID]++
+		},
+	}
+
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		id := "PR" + strconv.Itoa(i)
+		if changes["payment_request:"+id] != 1 || changes["payment:MOJO-"+id] != 1 {
+			t.Errorf("%s was not synced: %v", id, changes)
+		}
+	}
+
+	pd := &instamojo.PaymentDetails{}
+	if ok, err := s.Store.Get(instamojo.KindPayment, "MOJO-PR2", pd); !ok || err != nil || pd.Payment.Status != "Credit" {
+		t.Errorf("Got %v, %v, %+v", ok, err, pd.Payment)
+	}
+
+	// The modification of PR1 is the only change since the checkpoint,
+	// It is picked up once the clock has passed it
+	mu.Lock()
+	requests["PR1"] = time.Now().UTC()
+	mu.Unlock()
+	time.Sleep(1100 * time.Millisecond)
+
+	changes = map[string]int{}
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes["payment_request:PR1"] != 1 {
+		t.Errorf("Got changes %v, want PR1 and its payment", changes)
+	}
+}
@@ -0,0 +1,86 @@
+package instamojo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// verifyEndpoints are the endpoints of production and the sandbox that VerifyCredentials tells apart,
+// Tests point them at fake servers
+var verifyEndpoints = struct{ production, sandbox string }{ProductionURL, SandboxURL}
+
+// Errors returned by VerifyCredentials
+var (
+	ErrInvalidCredentials = errors.New("instamojo: invalid credentials")
+	ErrWrongEnvironment   = errors.New("instamojo: credentials are for the other environment")
+	ErrNetwork            = errors.New("instamojo: network error")
+)
+
+// VerifyCredentials makes a cheap authenticated request to check the credentials of the Config,
+// So a service can fail at startup instead of at its first payment.
+// It returns ErrWrongEnvironment when sandbox credentials are used on production or the other way around,
+// ErrInvalidCredentials when they are not valid at all and ErrNetwork when instamojo could not be reached.
+// The environments can only be told apart when BaseURL is not set
+func (c *Config) VerifyCredentials() error {
+	if c.BaseURL != "" {
+		return c.ping()
+	}
+
+	// The credentials are checked against the endpoint of the environment picked by SandboxMode,
+	// And against the other one only when they are rejected there
+	own, other := verifyEndpoints.production, verifyEndpoints.sandbox
+	if c.SandboxMode {
+		own, other = other, own
+	}
+
+	err := c.probe(own).ping()
+	if !errors.Is(err, ErrInvalidCredentials) {
+		return err
+	}
+
+	if c.probe(other).ping() == nil {
+		if c.SandboxMode {
+			return fmt.Errorf("%w: production credentials used in sandbox mode", ErrWrongEnvironment)
+		}
+		return fmt.Errorf("%w: sandbox credentials used in production", ErrWrongEnvironment)
+	}
+	return err
+}
+
+// probe returns a Config that sends requests to endpoint with the credentials and HTTP client of c,
+// Without its store, events, cache, metrics, middleware or retries
+func (c *Config) probe(endpoint string) *Config {
+	return &Config{
+		APIKey:      c.APIKey,
+		AuthToken:   c.AuthToken,
+		Credentials: c.Credentials,
+		APIVersion:  c.APIVersion,
+		HTTPClient:  c.HTTPClient,
+		UserAgent:   c.UserAgent,
+		endpoint:    strings.TrimRight(endpoint, "/"),
+	}
+}
+
+// ping lists a single payment request
+func (c *Config) ping() error {
+	resp, err := c.makeRequest(Operation{Name: "VerifyCredentials"}, "GET", c.apiURL("payment-requests/?limit=1"), nil)
+	if err != nil {
+		var ne net.Error
+		if errors.As(err, &ne) {
+			return fmt.Errorf("%w: %v", ErrNetwork, err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+		return nil
+	case 401:
+		return fmt.Errorf("%w: %v", ErrInvalidCredentials, unauthorized(resp))
+	}
+
+	return defaultResponse(resp)
+}
@@ -0,0 +1,93 @@
+package instamojo_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func TestVerifyCredentials(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(401)
+			fmt.Fprint(w, `{"success": false, "message": "Invalid token."}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": true, "payment_requests": []}`)
+	}))
+
+	tests := []struct {
+		key  string
+		want error
+	}{
+		{key: "key", want: nil},
+		{key: "wrong", want: instamojo.ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		cl, err := instamojo.NewClient(tt.key, "token", instamojo.WithBaseURL(ts.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.VerifyCredentials(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Got %v, want %v", tt.key, err, tt.want)
+		}
+	}
+
+	ts.Close()
+	cl, err := instamojo.NewClient("key", "token", instamojo.WithBaseURL(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cl.VerifyCredentials(); !errors.Is(err, instamojo.ErrNetwork) {
+		t.Errorf("Got %v, want %v", err, instamojo.ErrNetwork)
+	}
+}
+
+func TestVerifyCredentialsEnvironment(t *testing.T) {
+
+	// Each environment only accepts its own key
+	server := func(key string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Api-Key") != key {
+				w.WriteHeader(401)
+				fmt.Fprint(w, `{"success": false, "message": "Invalid token."}`)
+				return
+			}
+			fmt.Fprint(w, `{"success": true, "payment_requests": []}`)
+		}))
+	}
+	production, sandbox := server("live"), server("test")
+	defer production.Close()
+	defer sandbox.Close()
+	defer instamojo.SetVerifyEndpoints(production.URL, sandbox.URL)()
+
+	tests := []struct {
+		key     string
+		sandbox bool
+		want    error
+	}{
+		{key: "live", want: nil},
+		{key: "test", sandbox: true, want: nil},
+		{key: "test", want: instamojo.ErrWrongEnvironment},
+		{key: "live", sandbox: true, want: instamojo.ErrWrongEnvironment},
+		{key: "wrong", want: instamojo.ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		var opts []instamojo.Option
+		if tt.sandbox {
+			opts = append(opts, instamojo.WithSandbox())
+		}
+		cl, err := instamojo.NewClient(tt.key, "token", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cl.VerifyCredentials(); !errors.Is(err, tt.want) {
+			t.Errorf("%s in sandbox %v: Got %v, want %v", tt.key, tt.sandbox, err, tt.want)
+		}
+	}
+}
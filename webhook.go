@@ -0,0 +1,107 @@
+package instamojo
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// VerifyWebhookMAC checks the mac that instamojo sends with a webhook against the private salt
+// of the account. Webhooks that fail this check should be discarded
+func VerifyWebhookMAC(u url.Values, salt string) bool {
+	mac := u.Get("mac")
+	if mac == "" {
+		return false
+	}
+
+	return hmac.Equal([]byte(mac), []byte(webhookMAC(u, salt)))
+}
+
+// WebhookHandler returns a http.Handler that can be mounted at the webhook URL.
+// It verifies the mac of every webhook with salt, Or c.PrivateSalt if salt is empty,
+// And answers 400 to the ones that fail. Without any salt every webhook fails, Because anyone can make the mac of an empty salt.
+// Otherwise it saves and publishes the webhook like c.ParseWebhookResponse does and passes it to fn
+func (c *Config) WebhookHandler(salt string, fn func(*WebhookResponse)) http.Handler {
+	if salt == "" {
+		salt = c.PrivateSalt
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid webhook", http.StatusBadRequest)
+			return
+		}
+
+		if salt == "" || !VerifyWebhookMAC(r.PostForm, salt) {
+			if c.Metrics != nil {
+				c.Metrics.ObserveMACFailure()
+			}
+			http.Error(w, "invalid mac", http.StatusBadRequest)
+			return
+		}
+
+		wr := ParseWebhookResponse(r.PostForm)
+		c.recordWebhook(wr)
+		if c.Metrics != nil {
+			c.Metrics.ObserveWebhook(wr)
+		}
+		fn(wr)
+	})
+}
+
+// SignWebhookResponse is the reverse of ParseWebhookResponse. It encodes a WebhookResponse
+// and signs it with the private salt the same way instamojo does.
+// It is meant for exercising webhook endpoints in tests and staging
+func SignWebhookResponse(w *WebhookResponse, salt string) url.Values {
+
+	u := url.Values{
+		"fees":               []string{w.Fees},
+		"buyer":              []string{w.Buyer},
+		"status":             []string{w.Status},
+		"amount":             []string{w.Amount},
+		"longurl":            []string{w.Longurl},
+		"purpose":            []string{w.Purpose},
+		"currency":           []string{w.Currency},
+		"shorturl":           []string{w.Shorturl},
+		"payment_id":         []string{w.PaymentID},
+		"buyer_name":         []string{w.BuyerName},
+		"buyer_phone":        []string{w.BuyerPhone},
+		"payment_request_id": []string{w.PaymentRequestID},
+	}
+	u.Set("mac", webhookMAC(u, salt))
+
+	return u
+}
+
+// PostWebhook signs the WebhookResponse and POSTs it to target as a urlencoded form,
+// Just like instamojo does after a payment
+func PostWebhook(target string, w *WebhookResponse, salt string) (*http.Response, error) {
+	return http.PostForm(target, SignWebhookResponse(w, salt))
+}
+
+// webhookMAC is the hex encoded HMAC-SHA1 of all the values except mac,
+// sorted by their keys case insensitively and joined with "|"
+func webhookMAC(u url.Values, salt string) string {
+	keys := make([]string, 0, len(u))
+	for k := range u {
+		if k != "mac" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.ToLower(keys[i]) < strings.ToLower(keys[j])
+	})
+
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = u.Get(k)
+	}
+
+	h := hmac.New(sha1.New, []byte(salt))
+	h.Write([]byte(strings.Join(values, "|")))
+	return hex.EncodeToString(h.Sum(nil))
+}
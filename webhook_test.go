@@ -0,0 +1,70 @@
+package instamojo_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ishanjain28/instamojo"
+)
+
+func ExampleSignWebhookResponse() {
+
+	w := &instamojo.WebhookResponse{
+		PaymentID:        "MOJO5a06005J21512197",
+		Status:           "Credit",
+		Amount:           "2500.00",
+		Fees:             "125.00",
+		Currency:         "INR",
+		PaymentRequestID: "d66cb29dd059482e8072999f995c4eef",
+	}
+
+	values := instamojo.SignWebhookResponse(w, "private-salt")
+	fmt.Println(instamojo.VerifyWebhookMAC(values, "private-salt"))
+	fmt.Println(instamojo.VerifyWebhookMAC(values, "another-salt"))
+	// Output:
+	// true
+	// false
+}
+
+func TestPostWebhook(t *testing.T) {
+
+	want := instamojo.WebhookResponse{
+		PaymentID:        "MOJO5a06005J21512197",
+		Status:           "Credit",
+		Shorturl:         "https://imjo.in/NNxHg",
+		Longurl:          "https://www.instamojo.com/@portrack/077a7ff202f94d3e86ffe64511efa8a4",
+		Purpose:          "FIFA 16",
+		Amount:           "2500.00",
+		Fees:             "125.00",
+		Currency:         "INR",
+		Buyer:            "abc@xyz.com",
+		BuyerName:        "John Doe",
+		BuyerPhone:       "9999999999",
+		PaymentRequestID: "d66cb29dd059482e8072999f995c4eef",
+	}
+
+	var got instamojo.WebhookResponse
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if !instamojo.VerifyWebhookMAC(r.PostForm, "salt") {
+			t.Error("mac verification failed")
+		}
+		got = *instamojo.ParseWebhookResponse(r.PostForm)
+	}))
+	defer ts.Close()
+
+	resp, err := instamojo.PostWebhook(ts.URL, &want, "salt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	got.Mac = ""
+	if got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}